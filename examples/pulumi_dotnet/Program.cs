@@ -13,10 +13,10 @@ return await Deployment.RunAsync(() =>
     {
         Spec = new BlockStorageSpecArgs
         {
-            SizeGB = 32,
+            SizeGB = 50,
             SkuRef = new ReferenceArgs
             {
-                Resource = "my-SKU-reference",
+                Resource = "skus/seca.rd100",
             },
         },
         Workspace = ws.Metadata.Apply(m => m.Name),
@@ -4,9 +4,9 @@ const ws = new cape.workspace.Workspace('myWorkspace', { spec: {} }, {});
 const bs = new cape.storage.BlockStorage('myStorage', {
   workspace: ws.metadata.apply((m) => m.name),
   spec: {
-    sizeGB: 32,
+    sizeGB: 50,
     skuRef: {
-      resource: 'skus/standard',
+      resource: 'skus/seca.rd100',
     },
   },
 });
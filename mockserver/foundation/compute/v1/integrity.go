@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"fmt"
+
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/reference"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+)
+
+// instanceReferences resolves the boot and data volumes of an instance to
+// block storages. Volumes without a workspace are in the workspace of the
+// instance.
+func instanceReferences(scope store.Scope, instance models.Instance) []store.Reference {
+	deviceRefs := []models.Reference{instance.Spec.BootVolume.DeviceRef}
+	paths := []string{"spec.bootVolume.deviceRef"}
+	if instance.Spec.DataVolumes != nil {
+		for i, volume := range *instance.Spec.DataVolumes {
+			deviceRefs = append(deviceRefs, volume.DeviceRef)
+			paths = append(paths, fmt.Sprintf("spec.dataVolumes[%d].deviceRef", i))
+		}
+	}
+
+	refs := make([]store.Reference, 0, len(deviceRefs))
+	for i, deviceRef := range deviceRefs {
+		name, err := reference.Name(deviceRef, "block-storages")
+		refs = append(refs, store.Reference{
+			Path: paths[i],
+			Ref: integrity.Ref{
+				Kind:      "block-storage",
+				Tenant:    scope.Tenant,
+				Workspace: reference.Workspace(deviceRef, scope.Workspace),
+				Name:      name,
+			},
+			Err: err,
+		})
+	}
+	return refs
+}
@@ -2,225 +2,308 @@ package v1
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/labels"
+	"cape-project.eu/mockserver/internal/pagination"
+	"cape-project.eu/mockserver/internal/reference"
+	"cape-project.eu/mockserver/internal/store"
 	"cape-project.eu/mockserver/models"
 	"github.com/gin-gonic/gin"
 )
 
 type server struct {
-	mu        sync.RWMutex
-	instances map[string]models.Instance
+	*resources
+	skus      []catalog.InstanceSKU
+	integrity *integrity.Registry
 }
 
-func RegisterServer(router gin.IRouter) {
-	RegisterHandlersWithOptions(router, &server{
-		instances: map[string]models.Instance{},
-	}, GinServerOptions{
+func RegisterServer(router gin.IRouter, e env.Env) {
+	srv := &server{
+		resources: newResources(e),
+		skus:      e.InstanceSKUs,
+		integrity: e.Integrity,
+	}
+	if srv.skus == nil {
+		srv.skus = catalog.DefaultInstanceSKUs
+	}
+	srv.instances.Validate = srv.validateInstance
+	srv.instances.References = instanceReferences
+	srv.instances.OnWrite = keepPowerState
+	srv.instances.OnTransition = bootInstance
+	srv.instances.OnRestore = srv.resumePowerAction
+	RegisterHandlersWithOptions(router, srv, GinServerOptions{
 		BaseURL: "/providers/seca.compute",
 	})
 }
 
-func (s *server) ListSkus(c *gin.Context, _tenant models.TenantPathParam, _params ListSkusParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
-func (s *server) GetSku(c *gin.Context, _tenant models.TenantPathParam, _name models.ResourcePathParam) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
-func (s *server) ListInstances(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, _params ListInstancesParams) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *server) ListSkus(c *gin.Context, tenant models.TenantPathParam, params ListSkusParams) {
+	var selector labels.Selector
+	if params.Labels != nil {
+		var err error
+		if selector, err = labels.Parse(string(*params.Labels)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	items := make([]models.Instance, 0)
-	for _, instance := range s.instances {
-		if instance.Metadata == nil {
+	skus := make([]models.InstanceSku, 0, len(s.skus))
+	for _, def := range s.skus {
+		sku := instanceSKUFromCatalog(tenant, def)
+		if !selector.Matches(*sku.Labels) {
 			continue
 		}
-		if instance.Metadata.Tenant == tenant && instance.Metadata.Workspace == workspace {
-			items = append(items, instance)
-		}
+		skus = append(skus, sku)
 	}
 
-	c.JSON(http.StatusOK, InstanceIterator{
-		Items: items,
+	page, next, err := pagination.Page(skus, func(item models.InstanceSku) string {
+		return item.Metadata.Name
+	}, (*int)(params.Limit), (*string)(params.SkipToken))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, SkuIterator{
+		Items: page,
 		Metadata: models.ResponseMetadata{
-			Provider: "seca.compute/v1",
-			Resource: fmt.Sprintf("tenants/%s/workspaces/%s/instances", tenant, workspace),
-			Verb:     "list",
+			Provider:  "seca.compute/v1",
+			Resource:  fmt.Sprintf("tenants/%s/skus", tenant),
+			Verb:      "list",
+			SkipToken: next,
 		},
 	})
 }
 
-func (s *server) DeleteInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params DeleteInstanceParams) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := instanceKey(tenant, workspace, name)
-	if _, ok := s.instances[key]; !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "instance not found"})
-		return
+func (s *server) GetSku(c *gin.Context, tenant models.TenantPathParam, name models.ResourcePathParam) {
+	for _, def := range s.skus {
+		if def.Name == name {
+			c.JSON(http.StatusOK, instanceSKUFromCatalog(tenant, def))
+			return
+		}
 	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "sku not found"})
+}
 
-	delete(s.instances, key)
-	c.JSON(http.StatusAccepted, gin.H{
-		"deleted":   true,
-		"tenant":    tenant,
-		"workspace": workspace,
-		"name":      name,
-	})
+func (s *server) RestartInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params RestartInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, models.InstanceStatusPowerStateOn, powerActionRestart)
 }
 
-func (s *server) GetInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *server) StartInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params StartInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, models.InstanceStatusPowerStateOff, powerActionStart)
+}
 
-	instance, ok := s.instances[instanceKey(tenant, workspace, name)]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "instance not found"})
-		return
-	}
+func (s *server) StopInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params StopInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, models.InstanceStatusPowerStateOn, powerActionStop)
+}
+
+// Power actions in progress are recorded as the reason of a status
+// condition. The power state keeps its value until the action is done.
+const (
+	powerActionStart   = "starting"
+	powerActionStop    = "stopping"
+	powerActionRestart = "restarting"
+	powerStateChange   = "powerStateChange"
+)
 
-	c.JSON(http.StatusOK, instance)
+// powerActions maps the power actions to the power state the instance ends
+// up in.
+var powerActions = map[string]models.InstanceStatusPowerState{
+	powerActionStart:   models.InstanceStatusPowerStateOn,
+	powerActionStop:    models.InstanceStatusPowerStateOff,
+	powerActionRestart: models.InstanceStatusPowerStateOn,
 }
 
-func (s *server) CreateOrUpdateInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params CreateOrUpdateInstanceParams) {
-	var instance models.Instance
-	if err := c.ShouldBindJSON(&instance); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// changePowerState starts action on an active instance in power state from
+// and schedules its end. Any other state or an action in progress is a
+// conflict.
+func (s *server) changePowerState(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, from models.InstanceStatusPowerState, action string) {
+	scope := store.Scope{Tenant: tenant, Workspace: workspace, Name: name}
+	instance, ok := s.instances.Modify(c, scope, func(instance *models.Instance) (int, error) {
+		if instance.Status == nil || instance.Status.State == nil || *instance.Status.State != models.ResourceStateActive {
+			state := "unknown"
+			if instance.Status != nil && instance.Status.State != nil {
+				state = string(*instance.Status.State)
+			}
+			return http.StatusConflict, fmt.Errorf("instance is %s, power actions require an active instance", state)
+		}
+		if pending, ok := pendingPowerAction(instance); ok {
+			return http.StatusConflict, fmt.Errorf("instance is %s, wait for the power action to finish", pending)
+		}
+		if current := instance.Status.PowerState; current != from {
+			return http.StatusConflict, fmt.Errorf("instance power state is %s, expected %s", current, from)
+		}
+		s.instances.AddCondition(instance, action, fmt.Sprintf("Instance is %s", action))
+		return 0, nil
+	})
+	if !ok {
 		return
 	}
 
-	now := time.Now().UTC()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := instanceKey(tenant, workspace, name)
-	existing, exists := s.instances[key]
-	if !exists {
-		instance.Metadata = &models.RegionalWorkspaceResourceMetadata{
-			ApiVersion:      "v1",
-			CreatedAt:       now,
-			Kind:            "instance",
-			LastModifiedAt:  now,
-			Name:            name,
-			Provider:        "seca.compute",
-			Region:          "global",
-			Resource:        fmt.Sprintf("tenants/%s/workspaces/%s/instances/%s", tenant, workspace, name),
-			ResourceVersion: 1,
-			Tenant:          tenant,
-			Verb:            "put",
-			Workspace:       workspace,
-		}
-		setInstanceState(&instance, models.ResourceStatePending)
-
-		s.instances[key] = instance
-		version := instance.Metadata.ResourceVersion
-		s.scheduleInstanceStateTransition(tenant, workspace, name, version, 100*time.Millisecond, models.ResourceStateCreating)
-		s.scheduleInstanceStateTransition(tenant, workspace, name, version, 600*time.Millisecond, models.ResourceStateActive)
-		c.JSON(http.StatusCreated, instance)
-		return
+	delay := s.instances.Delays().Power
+	if action == powerActionRestart {
+		// a restart stops and starts the instance
+		delay *= 2
 	}
+	s.schedulePowerTransition(scope, delay, action)
+	c.JSON(http.StatusAccepted, instance)
+}
 
-	setInstanceState(&existing, models.ResourceStateActive)
-	s.instances[key] = existing
+// schedulePowerTransition ends the power action. Updates of the instance in
+// the meantime do not cancel it.
+func (s *server) schedulePowerTransition(scope store.Scope, delay time.Duration, action string) {
+	s.instances.After(delay, scope, func(instance *models.Instance) bool {
+		if pending, ok := pendingPowerAction(instance); !ok || pending != action {
+			return false
+		}
+		s.setPowerState(instance, powerActions[action])
+		return true
+	})
+}
 
-	if existing.Metadata != nil {
-		instance.Metadata = existing.Metadata
-	} else {
-		instance.Metadata = &models.RegionalWorkspaceResourceMetadata{}
+// resumePowerAction continues a power action of a restored instance.
+func (s *server) resumePowerAction(scope store.Scope, instance models.Instance) {
+	if action, ok := pendingPowerAction(&instance); ok {
+		s.schedulePowerTransition(scope, s.instances.Delays().Power, action)
 	}
+}
 
-	instance.Metadata.ApiVersion = "v1"
-	instance.Metadata.Kind = "instance"
-	instance.Metadata.Name = name
-	instance.Metadata.Provider = "seca.compute"
-	instance.Metadata.Region = "global"
-	instance.Metadata.Resource = fmt.Sprintf("tenants/%s/workspaces/%s/instances/%s", tenant, workspace, name)
-	instance.Metadata.Tenant = tenant
-	instance.Metadata.Verb = "put"
-	instance.Metadata.Workspace = workspace
-
-	if instance.Metadata.CreatedAt.IsZero() {
-		instance.Metadata.CreatedAt = now
+// pendingPowerAction returns the power action in progress: the latest power
+// condition if it is not the end of an action.
+func pendingPowerAction(instance *models.Instance) (string, bool) {
+	if instance.Status == nil {
+		return "", false
 	}
-	instance.Metadata.LastModifiedAt = now
-	instance.Metadata.ResourceVersion++
-	if instance.Metadata.ResourceVersion == 0 {
-		instance.Metadata.ResourceVersion = 1
+	conditions := instance.Status.Conditions
+	for i := len(conditions) - 1; i >= 0; i-- {
+		if conditions[i].Reason == nil {
+			continue
+		}
+		reason := *conditions[i].Reason
+		if reason == powerStateChange {
+			return "", false
+		}
+		if _, ok := powerActions[reason]; ok {
+			return reason, true
+		}
 	}
-	setInstanceState(&instance, models.ResourceStateUpdating)
-
-	s.instances[key] = instance
-	version := instance.Metadata.ResourceVersion
-	s.scheduleInstanceStateTransition(tenant, workspace, name, version, 500*time.Millisecond, models.ResourceStateActive)
-	c.JSON(http.StatusOK, instance)
+	return "", false
 }
 
-func (s *server) RestartInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params RestartInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+func (s *server) setPowerState(instance *models.Instance, powerState models.InstanceStatusPowerState) {
+	instance.Status.PowerState = powerState
+	s.instances.AddCondition(instance, powerStateChange, fmt.Sprintf("Instance power state is now %s", powerState))
 }
 
-func (s *server) StartInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params StartInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+// keepPowerState starts new instances powered off and keeps the power state
+// of updated ones.
+func keepPowerState(instance, existing *models.Instance) {
+	if instance.Status == nil {
+		instance.Status = &models.InstanceStatus{Conditions: []models.StatusCondition{}}
+	}
+	instance.Status.PowerState = models.InstanceStatusPowerStateOff
+	if existing != nil && existing.Status != nil {
+		instance.Status.PowerState = existing.Status.PowerState
+	}
 }
 
-func (s *server) StopInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params StopInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+// bootInstance powers on new instances once they are provisioned. Seeded
+// active instances without a power state are on as well.
+func bootInstance(instance *models.Instance, from *models.ResourceState, to models.ResourceState) {
+	if instance.Status == nil {
+		return
+	}
+	provisioned := from != nil && (*from == models.ResourceStatePending || *from == models.ResourceStateCreating)
+	seeded := from == nil && instance.Status.PowerState == ""
+	switch {
+	case to == models.ResourceStateActive && (provisioned || seeded):
+		instance.Status.PowerState = models.InstanceStatusPowerStateOn
+	case instance.Status.PowerState == "":
+		instance.Status.PowerState = models.InstanceStatusPowerStateOff
+	}
 }
 
-func (s *server) scheduleInstanceStateTransition(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, version int64, delay time.Duration, state models.ResourceState) {
-	go func() {
-		time.Sleep(delay)
-
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		key := instanceKey(tenant, workspace, name)
-		instance, ok := s.instances[key]
-		if !ok {
-			return
-		}
-
-		if instance.Metadata == nil || instance.Metadata.ResourceVersion != version {
-			return
-		}
-
-		setInstanceState(&instance, state)
-		s.instances[key] = instance
-	}()
+// validateInstance checks that the SKU of an instance is in the catalog and
+// that the image of its boot volume exists.
+func (s *server) validateInstance(scope store.Scope, instance *models.Instance) (int, error) {
+	if err := s.validateSKU(instance.Spec.SkuRef); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	if err := s.validateBootVolume(scope, instance); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	return 0, nil
 }
 
-func setInstanceState(instance *models.Instance, state models.ResourceState) {
-	if instance.Status == nil {
-		instance.Status = &models.InstanceStatus{
-			Conditions: []models.StatusCondition{},
-			PowerState: models.InstanceStatusPowerStateOff,
-		}
+// validateBootVolume checks that the image the boot volume of an instance was
+// created from still exists. Blank and restored volumes have no image. A boot
+// volume that does not resolve is rejected by the reference check.
+func (s *server) validateBootVolume(scope store.Scope, instance *models.Instance) error {
+	boot := instanceReferences(scope, *instance)[0]
+	if boot.Err != nil {
+		return nil
 	}
-	if instance.Status.Conditions == nil {
-		instance.Status.Conditions = []models.StatusCondition{}
+	resource, ok := s.integrity.Resource(boot.Ref)
+	if !ok {
+		return nil
 	}
-	if instance.Status.State != nil && *instance.Status.State == state {
-		return
+	blockStorage, ok := resource.(models.BlockStorage)
+	if !ok || blockStorage.Spec.SourceImageRef == nil {
+		return nil
 	}
+	image, err := reference.Name(*blockStorage.Spec.SourceImageRef, "images")
+	if err != nil || !s.integrity.Exists(integrity.Ref{Kind: "image", Tenant: scope.Tenant, Name: image}) {
+		return fmt.Errorf("%s: image of block storage %q not found", boot.Path, boot.Ref.Name)
+	}
+	return nil
+}
 
-	instance.Status.State = &state
-
-	msg := fmt.Sprintf("Instance is now in %s state", state)
-	reason := "stateChange"
-	instance.Status.Conditions = append(instance.Status.Conditions, models.StatusCondition{
-		LastTransitionAt: time.Now().UTC(),
-		Message:          &msg,
-		Reason:           &reason,
-		State:            state,
-	})
+// validateSKU checks that ref points to a SKU of the catalog.
+func (s *server) validateSKU(ref models.Reference) error {
+	name, err := reference.Name(ref, "skus")
+	if err != nil {
+		return fmt.Errorf("spec.skuRef: %w", err)
+	}
+	for _, sku := range s.skus {
+		if sku.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("spec.skuRef: sku %q not found", name)
 }
 
-func instanceKey(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) string {
-	return fmt.Sprintf("%s-%s-%s", tenant, workspace, name)
+func instanceSKUFromCatalog(tenant models.TenantPathParam, def catalog.InstanceSKU) models.InstanceSku {
+	skuLabels := models.Labels{
+		"provider":     "seca",
+		"architecture": def.Architecture,
+		"vCPU":         strconv.Itoa(def.VCPU),
+		"ram":          strconv.Itoa(def.RAM),
+	}
+	if def.Tier != "" {
+		skuLabels["tier"] = def.Tier
+	}
+	maps.Copy(skuLabels, def.Labels)
+	return models.InstanceSku{
+		Labels: &skuLabels,
+		Metadata: &models.SkuResourceMetadata{
+			ApiVersion: "v1",
+			Kind:       models.SkuResourceMetadataKindResourceKindInstanceSku,
+			Name:       def.Name,
+			Provider:   "seca.compute/v1",
+			Region:     store.Region,
+			Resource:   fmt.Sprintf("tenants/%s/skus/%s", tenant, def.Name),
+			Tenant:     tenant,
+			Verb:       "get",
+		},
+		Spec: &models.InstanceSkuSpec{
+			Ram:  def.RAM,
+			VCPU: def.VCPU,
+		},
+	}
 }
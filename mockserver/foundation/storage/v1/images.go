@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/reference"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+)
+
+// validateImage reports the size of the block storage an image is created
+// from. Unknown block storages are rejected by the reference check.
+func (s *server) validateImage(scope store.Scope, image *models.Image) (int, error) {
+	ref := blockStorageRef(scope, *image)
+	if ref.Err != nil {
+		return 0, nil
+	}
+	source, ok := s.blockStorages.Find(store.Scope{Tenant: ref.Ref.Tenant, Workspace: ref.Ref.Workspace, Name: ref.Ref.Name})
+	if !ok {
+		return 0, nil
+	}
+	image.Status = &models.ImageStatus{
+		Conditions: []models.StatusCondition{},
+		SizeGB:     &source.Spec.SizeGB,
+	}
+	return 0, nil
+}
+
+// validateBlockStorage checks the SKU of a block storage and that the image
+// it is created from fits into it.
+func (s *server) validateBlockStorage(scope store.Scope, blockStorage *models.BlockStorage) (int, error) {
+	if err := validateSKU(blockStorage.Spec.SkuRef); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	if blockStorage.Spec.SourceImageRef == nil {
+		return 0, nil
+	}
+	name, err := reference.Name(*blockStorage.Spec.SourceImageRef, "images")
+	if err != nil {
+		return 0, nil
+	}
+	image, ok := s.images.Find(store.Scope{Tenant: scope.Tenant, Name: name})
+	if !ok || image.Status == nil || image.Status.SizeGB == nil {
+		return 0, nil
+	}
+	if sizeGB := *image.Status.SizeGB; blockStorage.Spec.SizeGB < sizeGB {
+		return http.StatusUnprocessableEntity, fmt.Errorf("spec.sizeGB: image %q needs at least %d GB", name, sizeGB)
+	}
+	return 0, nil
+}
+
+var publicImagesPublishedAt = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Public images are created from read-only block storages in the public
+// workspace of the seca tenant.
+const (
+	publicTenant    = "seca"
+	publicWorkspace = "public"
+	publicSKU       = "seca.rd100"
+)
+
+// publicImages returns the catalog images as seen by tenant.
+func publicImages(tenant string) []models.Image {
+	images := make([]models.Image, 0, len(catalog.PublicImages))
+	for _, def := range catalog.PublicImages {
+		image, err := publicImage(tenant, def)
+		if err != nil {
+			log.Printf("public image %s: %v", def.Name, err)
+			continue
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// publicImage renders a catalog image as an active image of tenant.
+func publicImage(tenant models.TenantPathParam, def catalog.PublicImage) (models.Image, error) {
+	ref, err := resourceReference(fmt.Sprintf("seca.storage/v1/tenants/%s/workspaces/%s/block-storages/%s", publicTenant, publicWorkspace, def.Name))
+	if err != nil {
+		return models.Image{}, err
+	}
+	state := models.ResourceStateActive
+	sizeGB := def.SizeGB
+	imageLabels := models.Labels{
+		"provider":     "seca",
+		"visibility":   "public",
+		"os":           def.OS,
+		"version":      def.Version,
+		"architecture": def.Architecture,
+	}
+	return models.Image{
+		Labels: &imageLabels,
+		Metadata: &models.RegionalResourceMetadata{
+			ApiVersion:     "v1",
+			CreatedAt:      publicImagesPublishedAt,
+			Kind:           "image",
+			LastModifiedAt: publicImagesPublishedAt,
+			Name:           def.Name,
+			Provider:       "seca.storage",
+			Region:         store.Region,
+			Resource:       fmt.Sprintf("tenants/%s/images/%s", tenant, def.Name),
+			Tenant:         tenant,
+			Verb:           "get",
+		},
+		Spec: models.ImageSpec{
+			BlockStorageRef: ref,
+			CpuArchitecture: models.ImageSpecCpuArchitecture(def.Architecture),
+		},
+		Status: &models.ImageStatus{
+			Conditions: []models.StatusCondition{},
+			SizeGB:     &sizeGB,
+			State:      &state,
+		},
+	}, nil
+}
+
+// publicBlockStorages returns the sources of the public images, which only
+// exist in the public tenant.
+func publicBlockStorages(tenant string) []models.BlockStorage {
+	if tenant != publicTenant {
+		return nil
+	}
+	skuRef, err := resourceReference("skus/" + publicSKU)
+	if err != nil {
+		log.Printf("public block storages: %v", err)
+		return nil
+	}
+	blockStorages := make([]models.BlockStorage, 0, len(catalog.PublicImages))
+	for _, def := range catalog.PublicImages {
+		state := models.ResourceStateActive
+		blockStorageLabels := models.Labels{
+			"provider":   "seca",
+			"visibility": "public",
+		}
+		blockStorages = append(blockStorages, models.BlockStorage{
+			Labels: &blockStorageLabels,
+			Metadata: &models.RegionalWorkspaceResourceMetadata{
+				ApiVersion:     "v1",
+				CreatedAt:      publicImagesPublishedAt,
+				Kind:           "block-storage",
+				LastModifiedAt: publicImagesPublishedAt,
+				Name:           def.Name,
+				Provider:       "seca.storage",
+				Region:         store.Region,
+				Resource:       fmt.Sprintf("tenants/%s/workspaces/%s/block-storages/%s", tenant, publicWorkspace, def.Name),
+				Tenant:         tenant,
+				Verb:           "get",
+				Workspace:      publicWorkspace,
+			},
+			Spec: models.BlockStorageSpec{
+				SizeGB: def.SizeGB,
+				SkuRef: skuRef,
+			},
+			Status: &models.BlockStorageStatus{
+				Conditions: []models.StatusCondition{},
+				SizeGB:     def.SizeGB,
+				State:      &state,
+			},
+		})
+	}
+	return blockStorages
+}
+
+// resourceReference returns a reference to the resource at path.
+func resourceReference(path string) (models.Reference, error) {
+	var ref models.Reference
+	if err := json.Unmarshal([]byte(strconv.Quote(path)), &ref); err != nil {
+		return models.Reference{}, fmt.Errorf("reference %s: %w", path, err)
+	}
+	return ref, nil
+}
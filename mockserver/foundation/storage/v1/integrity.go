@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/reference"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+)
+
+// blockStorageReferences returns the image a block storage is created from.
+func blockStorageReferences(scope store.Scope, blockStorage models.BlockStorage) []store.Reference {
+	if blockStorage.Spec.SourceImageRef == nil {
+		return nil
+	}
+	name, err := reference.Name(*blockStorage.Spec.SourceImageRef, "images")
+	return []store.Reference{{
+		Path: "spec.sourceImageRef",
+		Ref:  integrity.Ref{Kind: "image", Tenant: scope.Tenant, Name: name},
+		Err:  err,
+	}}
+}
+
+// imageReferences returns the block storage an image is created from.
+func imageReferences(scope store.Scope, image models.Image) []store.Reference {
+	return []store.Reference{blockStorageRef(scope, image)}
+}
+
+// blockStorageRef resolves spec.blockStorageRef of an image, the reference
+// must name the workspace.
+func blockStorageRef(scope store.Scope, image models.Image) store.Reference {
+	ref := store.Reference{
+		Path: "spec.blockStorageRef",
+		Ref:  integrity.Ref{Kind: "block-storage", Tenant: scope.Tenant},
+	}
+	ref.Ref.Workspace, ref.Err = reference.Name(image.Spec.BlockStorageRef, "workspaces")
+	if ref.Err == nil {
+		ref.Ref.Name, ref.Err = reference.Name(image.Spec.BlockStorageRef, "block-storages")
+	}
+	return ref
+}
+
+// validateSKU checks that ref points to a storage SKU of the catalog.
+func validateSKU(ref models.Reference) error {
+	names := make([]string, 0, len(storageSKUCatalog))
+	for _, def := range storageSKUCatalog {
+		names = append(names, def.name)
+	}
+	name, err := reference.Name(ref, "skus")
+	if err != nil {
+		return fmt.Errorf("spec.skuRef: %w, use skus/ with one of %s", err, strings.Join(names, ", "))
+	}
+	if !slices.Contains(names, name) {
+		return fmt.Errorf("spec.skuRef: sku %q not found, use one of %s", name, strings.Join(names, ", "))
+	}
+	return nil
+}
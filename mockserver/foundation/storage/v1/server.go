@@ -3,19 +3,18 @@ package v1
 import (
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
-	"strings"
-	"sync"
-	"time"
 
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/labels"
+	"cape-project.eu/mockserver/internal/pagination"
+	"cape-project.eu/mockserver/internal/store"
 	"cape-project.eu/mockserver/models"
 	"github.com/gin-gonic/gin"
 )
 
 type server struct {
-	mu            sync.RWMutex
-	blockStorages map[string]models.BlockStorage
+	*resources
 }
 
 type storageSKUDefinition struct {
@@ -44,46 +43,54 @@ var storageSKUCatalog = []storageSKUDefinition{
 	{name: "seca.le40k", tier: "LE40K", iops: 40000, storageType: models.StorageSkuTypeLocalEphemeral, minVolumeSize: 50},
 }
 
-func RegisterServer(router gin.IRouter) {
-	RegisterHandlersWithOptions(router, &server{
-		blockStorages: map[string]models.BlockStorage{},
-	}, GinServerOptions{
+func RegisterServer(router gin.IRouter, e env.Env) {
+	srv := &server{resources: newResources(e)}
+	srv.blockStorages.Validate = srv.validateBlockStorage
+	srv.blockStorages.References = blockStorageReferences
+	srv.blockStorages.OnWrite = setBlockStorageSize
+	srv.blockStorages.Static = publicBlockStorages
+	srv.images.Static = publicImages
+	srv.images.Validate = srv.validateImage
+	srv.images.References = imageReferences
+	RegisterHandlersWithOptions(router, srv, GinServerOptions{
 		BaseURL: "/providers/seca.storage",
 	})
 }
 
-func (s *server) ListImages(c *gin.Context, _tenant models.TenantPathParam, _params ListImagesParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
-func (s *server) DeleteImage(c *gin.Context, _tenant models.TenantPathParam, _name models.ResourcePathParam, _params DeleteImageParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
-func (s *server) GetImage(c *gin.Context, _tenant models.TenantPathParam, _name models.ResourcePathParam) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
-func (s *server) CreateOrUpdateImage(c *gin.Context, _tenant models.TenantPathParam, _name models.ResourcePathParam, _params CreateOrUpdateImageParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
-
 func (s *server) ListSkus(c *gin.Context, tenant models.TenantPathParam, params ListSkusParams) {
+	var selector labels.Selector
+	if params.Labels != nil {
+		var err error
+		if selector, err = labels.Parse(string(*params.Labels)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	skus := make([]models.StorageSku, 0, len(storageSKUCatalog))
 	for _, def := range storageSKUCatalog {
 		sku := storageSKUFromDefinition(tenant, def)
-		if params.Labels != nil && !matchesLabelSelector(sku.Labels, string(*params.Labels)) {
+		if !selector.Matches(sku.Labels) {
 			continue
 		}
 		skus = append(skus, sku)
 	}
 
+	page, next, err := pagination.Page(skus, func(item models.StorageSku) string {
+		return item.Metadata.Name
+	}, (*int)(params.Limit), (*string)(params.SkipToken))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, SkuIterator{
-		Items: skus,
+		Items: page,
 		Metadata: models.ResponseMetadata{
-			Provider: "seca.storage/v1",
-			Resource: fmt.Sprintf("tenants/%s/skus", tenant),
-			Verb:     "list",
+			Provider:  "seca.storage/v1",
+			Resource:  fmt.Sprintf("tenants/%s/skus", tenant),
+			Verb:      "list",
+			SkipToken: next,
 		},
 	})
 }
@@ -98,187 +105,12 @@ func (s *server) GetSku(c *gin.Context, tenant models.TenantPathParam, name mode
 	c.JSON(http.StatusNotFound, gin.H{"error": "sku not found"})
 }
 
-func (s *server) ListBlockStorages(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, _params ListBlockStoragesParams) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	items := make([]models.BlockStorage, 0)
-	for _, blockStorage := range s.blockStorages {
-		if blockStorage.Metadata == nil {
-			continue
-		}
-		if blockStorage.Metadata.Tenant == tenant && blockStorage.Metadata.Workspace == workspace {
-			items = append(items, blockStorage)
-		}
-	}
-
-	c.JSON(http.StatusOK, BlockStorageIterator{
-		Items: items,
-		Metadata: models.ResponseMetadata{
-			Provider: "seca.storage/v1",
-			Resource: fmt.Sprintf("tenants/%s/workspaces/%s/block-storages", tenant, workspace),
-			Verb:     "list",
-		},
-	})
-}
-
-func (s *server) DeleteBlockStorage(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params DeleteBlockStorageParams) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := blockStorageKey(tenant, workspace, name)
-	if _, ok := s.blockStorages[key]; !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "block-storage not found"})
-		return
-	}
-
-	delete(s.blockStorages, key)
-	c.JSON(http.StatusAccepted, gin.H{
-		"deleted":   true,
-		"tenant":    tenant,
-		"workspace": workspace,
-		"name":      name,
-	})
-}
-
-func (s *server) GetBlockStorage(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	blockStorage, ok := s.blockStorages[blockStorageKey(tenant, workspace, name)]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "block-storage not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, blockStorage)
-}
-
-func (s *server) CreateOrUpdateBlockStorage(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params CreateOrUpdateBlockStorageParams) {
-	var blockStorage models.BlockStorage
-	if err := c.ShouldBindJSON(&blockStorage); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	now := time.Now().UTC()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := blockStorageKey(tenant, workspace, name)
-	existing, exists := s.blockStorages[key]
-	if !exists {
-		blockStorage.Metadata = &models.RegionalWorkspaceResourceMetadata{
-			ApiVersion:      "v1",
-			CreatedAt:       now,
-			Kind:            "block-storage",
-			LastModifiedAt:  now,
-			Name:            name,
-			Provider:        "seca.storage",
-			Region:          "global",
-			Resource:        fmt.Sprintf("tenants/%s/workspaces/%s/block-storages/%s", tenant, workspace, name),
-			ResourceVersion: 1,
-			Tenant:          tenant,
-			Verb:            "put",
-			Workspace:       workspace,
-		}
-		setBlockStorageState(&blockStorage, models.ResourceStatePending)
-
-		s.blockStorages[key] = blockStorage
-		version := blockStorage.Metadata.ResourceVersion
-		s.scheduleBlockStorageStateTransition(tenant, workspace, name, version, 100*time.Millisecond, models.ResourceStateCreating)
-		s.scheduleBlockStorageStateTransition(tenant, workspace, name, version, 600*time.Millisecond, models.ResourceStateActive)
-		c.JSON(http.StatusCreated, blockStorage)
-		return
-	}
-
-	setBlockStorageState(&existing, models.ResourceStateActive)
-	s.blockStorages[key] = existing
-
-	if existing.Metadata != nil {
-		blockStorage.Metadata = existing.Metadata
-	} else {
-		blockStorage.Metadata = &models.RegionalWorkspaceResourceMetadata{}
-	}
-
-	blockStorage.Metadata.ApiVersion = "v1"
-	blockStorage.Metadata.Kind = "block-storage"
-	blockStorage.Metadata.Name = name
-	blockStorage.Metadata.Provider = "seca.storage"
-	blockStorage.Metadata.Region = "global"
-	blockStorage.Metadata.Resource = fmt.Sprintf("tenants/%s/workspaces/%s/block-storages/%s", tenant, workspace, name)
-	blockStorage.Metadata.Tenant = tenant
-	blockStorage.Metadata.Verb = "put"
-	blockStorage.Metadata.Workspace = workspace
-
-	if blockStorage.Metadata.CreatedAt.IsZero() {
-		blockStorage.Metadata.CreatedAt = now
-	}
-	blockStorage.Metadata.LastModifiedAt = now
-	blockStorage.Metadata.ResourceVersion++
-	if blockStorage.Metadata.ResourceVersion == 0 {
-		blockStorage.Metadata.ResourceVersion = 1
-	}
-	setBlockStorageState(&blockStorage, models.ResourceStateUpdating)
-
-	s.blockStorages[key] = blockStorage
-	version := blockStorage.Metadata.ResourceVersion
-	s.scheduleBlockStorageStateTransition(tenant, workspace, name, version, 500*time.Millisecond, models.ResourceStateActive)
-	c.JSON(http.StatusOK, blockStorage)
-}
-
-func (s *server) scheduleBlockStorageStateTransition(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, version int64, delay time.Duration, state models.ResourceState) {
-	go func() {
-		time.Sleep(delay)
-
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		key := blockStorageKey(tenant, workspace, name)
-		blockStorage, ok := s.blockStorages[key]
-		if !ok {
-			return
-		}
-
-		if blockStorage.Metadata == nil || blockStorage.Metadata.ResourceVersion != version {
-			return
-		}
-
-		setBlockStorageState(&blockStorage, state)
-		s.blockStorages[key] = blockStorage
-	}()
-}
-
-func setBlockStorageState(blockStorage *models.BlockStorage, state models.ResourceState) {
+// setBlockStorageSize reports the size of the spec in the status.
+func setBlockStorageSize(blockStorage, _ *models.BlockStorage) {
 	if blockStorage.Status == nil {
-		blockStorage.Status = &models.BlockStorageStatus{
-			Conditions: []models.StatusCondition{},
-			SizeGB:     blockStorage.Spec.SizeGB,
-		}
-	}
-	if blockStorage.Status.Conditions == nil {
-		blockStorage.Status.Conditions = []models.StatusCondition{}
+		blockStorage.Status = &models.BlockStorageStatus{Conditions: []models.StatusCondition{}}
 	}
-	if blockStorage.Status.State != nil && *blockStorage.Status.State == state {
-		return
-	}
-
 	blockStorage.Status.SizeGB = blockStorage.Spec.SizeGB
-	blockStorage.Status.State = &state
-
-	msg := fmt.Sprintf("BlockStorage is now in %s state", state)
-	reason := "stateChange"
-	blockStorage.Status.Conditions = append(blockStorage.Status.Conditions, models.StatusCondition{
-		LastTransitionAt: time.Now().UTC(),
-		Message:          &msg,
-		Reason:           &reason,
-		State:            state,
-	})
-}
-
-func blockStorageKey(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) string {
-	return fmt.Sprintf("%s-%s-%s", tenant, workspace, name)
 }
 
 func storageSKUFromDefinition(tenant models.TenantPathParam, def storageSKUDefinition) models.StorageSku {
@@ -295,7 +127,7 @@ func storageSKUFromDefinition(tenant models.TenantPathParam, def storageSKUDefin
 			Kind:       models.SkuResourceMetadataKindResourceKindStorageSku,
 			Name:       def.name,
 			Provider:   "seca.storage/v1",
-			Region:     "eu-central-1",
+			Region:     store.Region,
 			Resource:   fmt.Sprintf("tenants/%s/skus/%s", tenant, def.name),
 			Tenant:     tenant,
 			Verb:       "get",
@@ -307,106 +139,3 @@ func storageSKUFromDefinition(tenant models.TenantPathParam, def storageSKUDefin
 		},
 	}
 }
-
-func matchesLabelSelector(labels models.Labels, selector string) bool {
-	selector = strings.TrimSpace(selector)
-	if selector == "" {
-		return true
-	}
-
-	filters := strings.SplitSeq(selector, ",")
-	for filter := range filters {
-		if !matchesLabelFilter(labels, strings.TrimSpace(filter)) {
-			return false
-		}
-	}
-	return true
-}
-
-func matchesLabelFilter(labels models.Labels, filter string) bool {
-	if filter == "" {
-		return true
-	}
-
-	operators := []string{"!=", ">=", "<=", "=", ">", "<"}
-	for _, operator := range operators {
-		if !strings.Contains(filter, operator) {
-			continue
-		}
-
-		parts := strings.SplitN(filter, operator, 2)
-		if len(parts) != 2 {
-			return false
-		}
-
-		keyPattern := strings.TrimSpace(parts[0])
-		valuePattern := strings.TrimSpace(parts[1])
-		if keyPattern == "" {
-			return false
-		}
-
-		switch operator {
-		case "=":
-			for key, value := range labels {
-				if matchPattern(keyPattern, key) && matchPattern(valuePattern, value) {
-					return true
-				}
-			}
-			return false
-		case "!=":
-			for key, value := range labels {
-				if matchPattern(keyPattern, key) && matchPattern(valuePattern, value) {
-					return false
-				}
-			}
-			return true
-		default:
-			target, err := strconv.ParseFloat(valuePattern, 64)
-			if err != nil {
-				return false
-			}
-			for key, value := range labels {
-				if !matchPattern(keyPattern, key) {
-					continue
-				}
-				current, err := strconv.ParseFloat(value, 64)
-				if err != nil {
-					continue
-				}
-				switch operator {
-				case ">":
-					if current > target {
-						return true
-					}
-				case "<":
-					if current < target {
-						return true
-					}
-				case ">=":
-					if current >= target {
-						return true
-					}
-				case "<=":
-					if current <= target {
-						return true
-					}
-				}
-			}
-			return false
-		}
-	}
-
-	return false
-}
-
-func matchPattern(pattern string, value string) bool {
-	if strings.Contains(pattern, "*") {
-		regex := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*") + "$"
-		re, err := regexp.Compile(regex)
-		if err != nil {
-			return false
-		}
-		return re.MatchString(value)
-	}
-	return value == pattern
-}
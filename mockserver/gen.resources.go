@@ -0,0 +1,401 @@
+//go:build ignore
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// gen.resources.go implements the operations of every resource in the specs
+// on top of store.Store, so new resources work without hand-written code.
+// It reads the resources from the specs and the exact method signatures from
+// the ServerInterface generated by oapi-codegen and writes per package:
+//
+//   - resources.gen.go: a store per resource and all operations of the
+//     ServerInterface, operations that are not CRUD respond with 501.
+//   - server.gen.go: the server and RegisterServer, unless the package has a
+//     hand-written server.go embedding *resources.
+//
+// pkg/mockserver/servers.gen.go registers the servers of all packages.
+
+const SpecDir = "../ext/secapi/spec"
+const ModulePath = "cape-project.eu/mockserver"
+
+var resourcesTemplate = readTemplate("resources", "internal/codegen/resources.tmpl")
+var serverTemplate = readTemplate("server", "internal/codegen/server.tmpl")
+var serversTemplate = readTemplate("servers", "internal/codegen/servers.tmpl")
+
+type resourceDef struct {
+	Type       string
+	Field      string
+	Kind       string
+	Collection string
+	Workspaced bool
+}
+
+type operationDef struct {
+	Name   string
+	Params string
+	// Store is the field of the store implementing the operation and Call
+	// the store method, both empty if the operation is not implemented.
+	Store string
+	Call  string
+	Scope string
+	// List arguments.
+	Labels    string
+	Limit     string
+	SkipToken string
+}
+
+type packageDef struct {
+	Dir        string
+	Package    string
+	ImportPath string
+	Alias      string
+	Provider   string
+	APIVersion string
+	Resources  []resourceDef
+	Operations []operationDef
+	// NeedsHTTP and NeedsModels tell which imports resources.gen.go uses.
+	NeedsHTTP   bool
+	NeedsModels bool
+}
+
+func main() {
+	entries, err := os.ReadDir(SpecDir)
+	if err != nil {
+		fmt.Printf("error reading specs: %v\n", err)
+		os.Exit(1)
+	}
+
+	var packages []packageDef
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".yaml" {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(name, ".yaml"), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		pkg, err := buildPackage(filepath.Join(SpecDir, name), parts)
+		if err != nil {
+			fmt.Printf("skipping %s: %v\n", name, err)
+			continue
+		}
+
+		writeTemplate(filepath.Join(pkg.Dir, "resources.gen.go"), pkg, resourcesTemplate)
+		if _, err := os.Stat(filepath.Join(pkg.Dir, "server.go")); os.IsNotExist(err) {
+			writeTemplate(filepath.Join(pkg.Dir, "server.gen.go"), pkg, serverTemplate)
+		}
+		packages = append(packages, pkg)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].ImportPath < packages[j].ImportPath
+	})
+	writeTemplate(filepath.Join("pkg", "mockserver", "servers.gen.go"), packages, serversTemplate)
+}
+
+func buildPackage(specPath string, parts []string) (packageDef, error) {
+	dir := filepath.Join(parts...)
+	pkg := packageDef{
+		Dir:        dir,
+		Package:    parts[len(parts)-1],
+		ImportPath: ModulePath + "/" + strings.Join(parts, "/"),
+		Alias:      strings.Join(parts[1:], "_"),
+		Provider:   "seca." + parts[len(parts)-2],
+		APIVersion: parts[len(parts)-1],
+	}
+
+	model, err := buildV3Model(specPath)
+	if err != nil {
+		return pkg, err
+	}
+	methods, paramFields, err := parseServerInterface(dir)
+	if err != nil {
+		return pkg, err
+	}
+
+	// operations maps operation names to the resource and store method.
+	operations := map[string]operationDef{}
+	paths := map[string]*v3high.PathItem{}
+	if model.Model.Paths != nil && model.Model.Paths.PathItems != nil {
+		for path, item := range model.Model.Paths.PathItems.FromOldest() {
+			paths[path] = item
+		}
+	}
+	for path, item := range paths {
+		if item == nil || item.Put == nil || item.Get == nil || item.Delete == nil {
+			continue
+		}
+		typeName := strings.TrimPrefix(operationName(item.Put.OperationId), "CreateOrUpdate")
+		if ref := requestBodyRef(item.Put); ref != "" {
+			typeName = ref[strings.LastIndex(ref, "/")+1:]
+		}
+		collection := collectionFromPath(path)
+		resource := resourceDef{
+			Type:       typeName,
+			Field:      lowerCamel(collection),
+			Kind:       kebabCase(typeName),
+			Collection: collection,
+			Workspaced: strings.Contains(path, "{workspace}"),
+		}
+		pkg.Resources = append(pkg.Resources, resource)
+
+		operations[operationName(item.Get.OperationId)] = operationDef{Store: resource.Field, Call: "Get"}
+		operations[operationName(item.Put.OperationId)] = operationDef{Store: resource.Field, Call: "Put"}
+		operations[operationName(item.Delete.OperationId)] = operationDef{Store: resource.Field, Call: "Delete"}
+		if list, ok := paths[path[:strings.LastIndex(path, "/")]]; ok && list != nil && list.Get != nil {
+			operations[operationName(list.Get.OperationId)] = operationDef{Store: resource.Field, Call: "List"}
+		}
+	}
+	sort.Slice(pkg.Resources, func(i, j int) bool {
+		return pkg.Resources[i].Type < pkg.Resources[j].Type
+	})
+
+	for _, method := range methods {
+		op := operations[method.name]
+		op.Name = method.name
+		op.Params = method.params
+		if op.Call != "" {
+			var scope []string
+			for _, param := range []string{"tenant", "workspace", "name"} {
+				if method.hasParam(param) {
+					scope = append(scope, fmt.Sprintf("%s: %s", strings.ToUpper(param[:1])+param[1:], param))
+				}
+			}
+			op.Scope = "store.Scope{" + strings.Join(scope, ", ") + "}"
+		}
+		if op.Call == "List" {
+			fields := paramFields[method.name+"Params"]
+			op.Labels, op.Limit, op.SkipToken = "nil", "nil", "nil"
+			if fields["Labels"] {
+				op.Labels = "(*string)(params.Labels)"
+			}
+			if fields["Limit"] {
+				op.Limit = "(*int)(params.Limit)"
+			}
+			if fields["SkipToken"] {
+				op.SkipToken = "(*string)(params.SkipToken)"
+			}
+		}
+		pkg.Operations = append(pkg.Operations, op)
+		pkg.NeedsHTTP = pkg.NeedsHTTP || op.Call == ""
+		pkg.NeedsModels = pkg.NeedsModels || strings.Contains(op.Params, "models.")
+	}
+	pkg.NeedsModels = pkg.NeedsModels || len(pkg.Resources) > 0
+	return pkg, nil
+}
+
+type method struct {
+	name   string
+	params string
+	names  []string
+}
+
+func (m method) hasParam(name string) bool {
+	return slices.Contains(m.names, name)
+}
+
+// parseServerInterface returns the methods of the ServerInterface generated
+// by oapi-codegen in dir and the fields of the parameter structs.
+func parseServerInterface(dir string) ([]method, map[string]map[string]bool, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.gen.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var methods []method
+	paramFields := map[string]map[string]bool{}
+	aliases := map[string]string{}
+	for _, path := range files {
+		base := filepath.Base(path)
+		if base == "resources.gen.go" || base == "server.gen.go" {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			switch typ := spec.Type.(type) {
+			case *ast.InterfaceType:
+				if spec.Name.Name != "ServerInterface" {
+					return false
+				}
+				for _, field := range typ.Methods.List {
+					fn, ok := field.Type.(*ast.FuncType)
+					if !ok || len(field.Names) == 0 {
+						continue
+					}
+					m := method{name: field.Names[0].Name}
+					var params []string
+					for _, param := range fn.Params.List {
+						var typeText bytes.Buffer
+						_ = printer.Fprint(&typeText, fset, param.Type)
+						for _, name := range param.Names {
+							m.names = append(m.names, name.Name)
+							params = append(params, name.Name+" "+typeText.String())
+						}
+					}
+					m.params = strings.Join(params, ", ")
+					methods = append(methods, m)
+				}
+			case *ast.Ident:
+				// parameter structs shared between operations are aliases
+				if spec.Assign.IsValid() && strings.HasSuffix(spec.Name.Name, "Params") {
+					aliases[spec.Name.Name] = typ.Name
+				}
+			case *ast.StructType:
+				if !strings.HasSuffix(spec.Name.Name, "Params") {
+					return false
+				}
+				fields := map[string]bool{}
+				for _, field := range typ.Fields.List {
+					for _, name := range field.Names {
+						fields[name.Name] = true
+					}
+				}
+				paramFields[spec.Name.Name] = fields
+			}
+			return false
+		})
+	}
+	if methods == nil {
+		return nil, nil, fmt.Errorf("no ServerInterface in %s, run gen_stubs.sh first", dir)
+	}
+	for name, target := range aliases {
+		paramFields[name] = paramFields[target]
+	}
+	return methods, paramFields, nil
+}
+
+func buildV3Model(file string) (*libopenapi.DocumentModel[v3high.Document], error) {
+	spec, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	document, err := libopenapi.NewDocumentWithConfiguration(spec, &datamodel.DocumentConfiguration{
+		BypassDocumentCheck: true,
+		BasePath:            filepath.Dir(file),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	model, err := document.BuildV3Model()
+	if err != nil {
+		return nil, fmt.Errorf("build v3 model for %s: %w", file, err)
+	}
+	return model, nil
+}
+
+func requestBodyRef(op *v3high.Operation) string {
+	if op.RequestBody == nil || op.RequestBody.Content == nil {
+		return ""
+	}
+	for _, media := range op.RequestBody.Content.FromOldest() {
+		if media != nil && media.Schema != nil && media.Schema.IsReference() {
+			return media.Schema.GetReference()
+		}
+	}
+	return ""
+}
+
+func collectionFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(segments[i], "{") {
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+// operationName returns the Go method name oapi-codegen uses for an
+// operation id.
+func operationName(operationID string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range operationID {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lowerCamel turns a collection like block-storages into blockStorages.
+func lowerCamel(collection string) string {
+	name := operationName(collection)
+	if name == "" {
+		return ""
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func kebabCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func readTemplate(name, path string) *template.Template {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return template.Must(template.New(name).Parse(string(data)))
+}
+
+func writeTemplate(path string, data any, tmpl *template.Template) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		fmt.Printf("error rendering %s: %v\n", path, err)
+		return
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		fmt.Printf("error formatting %s: %v\n", path, err)
+		source = buf.Bytes()
+	}
+	if err := os.WriteFile(path, source, 0o644); err != nil {
+		fmt.Printf("error writing %s: %v\n", path, err)
+	}
+}
@@ -0,0 +1,234 @@
+package admin
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"cape-project.eu/mockserver/internal/auth"
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/faults"
+	"cape-project.eu/mockserver/internal/state"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes adds the /admin endpoints to inspect and replace the state,
+// the clock and the injected faults of the mockserver, and to issue tokens.
+func RegisterRoutes(router gin.IRouter, e env.Env) {
+	registry, clk := e.Registry, e.Clock
+	group := router.Group("/admin")
+	group.GET("/snapshot", snapshot(registry))
+	group.POST("/restore", restore(registry))
+	group.POST("/reset", reset(registry))
+	group.POST("/fixtures", loadFixture(registry))
+	group.GET("/state", dump(registry))
+	group.GET("/providers", providers(registry))
+	group.GET("/clock", getClock(clk))
+	group.POST("/clock/advance", advanceClock(clk))
+	group.GET("/faults", listFaults(e.Faults))
+	group.POST("/faults", addFaults(e.Faults))
+	group.DELETE("/faults", clearFaults(e.Faults))
+	group.DELETE("/faults/:id", removeFault(e.Faults))
+	if e.Auth != nil {
+		group.POST("/tokens", issueToken(e.Auth))
+	}
+}
+
+func snapshot(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snapshot, err := registry.Snapshot()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, snapshot)
+	}
+}
+
+func restore(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var snapshot state.Snapshot
+		if err := c.ShouldBindJSON(&snapshot); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := registry.Restore(snapshot); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"restored": registry.Names()})
+	}
+}
+
+func reset(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		registry.Reset()
+		c.JSON(http.StatusOK, gin.H{"reset": registry.Names()})
+	}
+}
+
+// loadFixture seeds the resources of a YAML or JSON fixture. With
+// ?reset=true all stores are cleared first.
+func loadFixture(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		fixture, err := state.ParseFixture(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("reset") == "true" {
+			registry.Reset()
+		}
+		if err := registry.Seed(fixture); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		counts := map[string]int{}
+		for name, items := range fixture {
+			counts[name] = len(items)
+		}
+		c.JSON(http.StatusOK, gin.H{"seeded": counts})
+	}
+}
+
+// dump returns all resources as a fixture, as YAML with ?format=yaml.
+func dump(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fixture, err := registry.Dump()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("format") != "yaml" {
+			c.JSON(http.StatusOK, fixture)
+			return
+		}
+		data, err := fixture.YAML()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", data)
+	}
+}
+
+func providers(registry *state.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"providers": registry.Names()})
+	}
+}
+
+func getClock(clk clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manual, ok := clk.(*clock.Manual)
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{"now": clk.Now(), "manual": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"now": manual.Now(), "manual": true, "pending": manual.Pending()})
+	}
+}
+
+// advanceClock moves a manual clock forward by ?by=<duration>, running all
+// state transitions that became due.
+func advanceClock(clk clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manual, ok := clk.(*clock.Manual)
+		if !ok {
+			c.JSON(http.StatusConflict, gin.H{"error": "the clock is not manual, start the server with --clock=manual"})
+			return
+		}
+		by, err := time.ParseDuration(c.Query("by"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration: " + err.Error()})
+			return
+		}
+		if by < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must not be negative"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"now": manual.Advance(by), "pending": manual.Pending()})
+	}
+}
+
+func listFaults(injector *faults.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"rules": injector.Rules()})
+	}
+}
+
+// addFaults adds a YAML or JSON list of rules. A rule replaces an existing
+// rule with the same id.
+func addFaults(injector *faults.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rules, err := faults.ParseRules(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		added, err := injector.Add(rules...)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"rules": added})
+	}
+}
+
+func clearFaults(injector *faults.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		injector.Clear()
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func removeFault(injector *faults.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !injector.Remove(c.Param("id")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "fault rule not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// issueToken responds with a JWT for the principal in the body, valid for
+// ttl (default 1h).
+func issueToken(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			auth.Principal
+			TTL string `json:"ttl"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ttl := time.Hour
+		if request.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(request.TTL); err != nil || ttl <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration"})
+				return
+			}
+		}
+		token, expiresAt, err := authenticator.Issue(request.Principal, ttl)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"token": token, "expiresAt": expiresAt})
+	}
+}
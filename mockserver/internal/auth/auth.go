@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+	"go.yaml.in/yaml/v4"
+)
+
+// Principal is the caller of a request. Tenants may contain "*" for all
+// tenants.
+type Principal struct {
+	Subject string   `yaml:"subject" json:"subject"`
+	Tenants []string `yaml:"tenants" json:"tenants"`
+	Roles   []string `yaml:"roles" json:"roles"`
+}
+
+// Token is a static bearer token.
+type Token struct {
+	Token     string `yaml:"token"`
+	Principal `yaml:",inline"`
+}
+
+// Config of the authentication, e.g.
+//
+//	tokens:
+//	  - {token: t1-admin, subject: alice, tenants: [t1], roles: [editor]}
+//	  - {token: root, subject: ci, roles: [admin]}
+//	roles:
+//	  compute-operator: ["seca.compute:*", "*:read"]
+type Config struct {
+	Tokens []Token `yaml:"tokens"`
+	// Roles adds roles to the default viewer and editor roles. Permissions
+	// are <provider>:<read|write> patterns.
+	Roles map[string][]string `yaml:"roles"`
+	// Issuer and Audience are set in issued JWTs and checked on requests.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+var DefaultRoles = map[string][]string{
+	"viewer": {"*:read"},
+	"editor": {"*:*"},
+}
+
+// AdminRole grants access to the admin endpoints, e.g. to issue tokens. The
+// permissions of other roles never do.
+const AdminRole = "admin"
+
+const DefaultIssuer = "mockserver"
+
+func ParseConfig(data []byte) (Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("parse auth config: %w", err)
+	}
+	for i, token := range config.Tokens {
+		if token.Token == "" {
+			return Config{}, fmt.Errorf("token %d: token is required", i)
+		}
+	}
+	for role, permissions := range config.Roles {
+		for _, permission := range permissions {
+			if _, err := path.Match(permission, ""); err != nil || !strings.Contains(permission, ":") {
+				return Config{}, fmt.Errorf("role %s: invalid permission %q, expected <provider>:<read|write>", role, permission)
+			}
+		}
+	}
+	return config, nil
+}
+
+func ReadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return ParseConfig(data)
+}
+
+// Authenticator checks the bearer tokens of requests. JWTs are signed with a
+// key generated on start, so they are only valid for this process.
+type Authenticator struct {
+	config Config
+	roles  map[string][]string
+	clock  clock.Clock
+	key    *ecdsa.PrivateKey
+	keyID  string
+}
+
+func New(config Config, clk clock.Clock) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	if config.Issuer == "" {
+		config.Issuer = DefaultIssuer
+	}
+	roles := make(map[string][]string, len(DefaultRoles)+len(config.Roles))
+	maps.Copy(roles, DefaultRoles)
+	maps.Copy(roles, config.Roles)
+	return &Authenticator{
+		config: config,
+		roles:  roles,
+		clock:  clk,
+		key:    key,
+		keyID:  keyID(&key.PublicKey),
+	}, nil
+}
+
+var ErrMissingToken = errors.New("missing bearer token")
+
+// Authenticate returns the principal of a bearer token, a static token or a
+// JWT issued by Issue.
+func (a *Authenticator) Authenticate(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrMissingToken
+	}
+	for _, static := range a.config.Tokens {
+		if static.Token == token {
+			return static.Principal, nil
+		}
+	}
+	if strings.Count(token, ".") != 2 {
+		return Principal{}, errors.New("invalid token: unknown token")
+	}
+	principal, err := a.verify(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("invalid token: %w", err)
+	}
+	return principal, nil
+}
+
+// Authorize checks that principal may access tenant and use verb, read or
+// write, on provider.
+func (a *Authenticator) Authorize(principal Principal, tenant, provider, verb string) error {
+	if tenant != "" && !slices.Contains(principal.Tenants, "*") && !slices.Contains(principal.Tenants, tenant) {
+		return fmt.Errorf("%s has no access to tenant %s", principal.name(), tenant)
+	}
+	permission := provider + ":" + verb
+	for _, role := range principal.Roles {
+		for _, pattern := range a.roles[role] {
+			if ok, _ := path.Match(pattern, permission); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%s is not allowed to %s %s", principal.name(), verb, provider)
+}
+
+func (p Principal) name() string {
+	if p.Subject == "" {
+		return "token"
+	}
+	return p.Subject
+}
+
+// Issue returns a JWT for principal that expires after ttl.
+func (a *Authenticator) Issue(principal Principal, ttl time.Duration) (string, time.Time, error) {
+	now := a.clock.Now()
+	expiresAt := now.Add(ttl)
+	token, err := a.sign(claims{
+		Issuer:    a.config.Issuer,
+		Audience:  a.config.Audience,
+		Subject:   principal.Subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Tenants:   principal.Tenants,
+		Roles:     principal.Roles,
+	})
+	return token, expiresAt, err
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+)
+
+func newAuthenticator(t *testing.T, config Config) (*Authenticator, *clock.Manual) {
+	t.Helper()
+	clk := clock.NewManual(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	a, err := New(config, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, clk
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	a, clk := newAuthenticator(t, Config{Audience: "cape"})
+	other, _ := newAuthenticator(t, Config{Audience: "cape"})
+	now := clk.Now().Unix()
+	valid := claims{Issuer: DefaultIssuer, Audience: "cape", Subject: "bob", IssuedAt: now, ExpiresAt: now + 60, Tenants: []string{"t1"}, Roles: []string{"viewer"}}
+
+	sign := func(signer *Authenticator, change func(c *claims)) string {
+		c := valid
+		change(&c)
+		token, err := signer.sign(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	validToken := sign(a, func(*claims) {})
+	parts := strings.Split(validToken, ".")
+
+	unsigned := func(alg string) string {
+		head, _ := json.Marshal(header{Algorithm: alg, Type: "JWT"})
+		return encoding.EncodeToString(head) + "." + parts[1] + "."
+	}
+	tampered := func() string {
+		body, _ := json.Marshal(claims{Issuer: DefaultIssuer, Audience: "cape", Subject: "bob", ExpiresAt: now + 60, Tenants: []string{"*"}, Roles: []string{"editor"}})
+		return parts[0] + "." + encoding.EncodeToString(body) + "." + parts[2]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "valid", token: validToken},
+		{name: "expired", token: sign(a, func(c *claims) { c.ExpiresAt = now }), wantErr: "token expired"},
+		{name: "not yet valid", token: sign(a, func(c *claims) { c.NotBefore = now + 10 }), wantErr: "token not yet valid"},
+		{name: "wrong issuer", token: sign(a, func(c *claims) { c.Issuer = "other" }), wantErr: "unexpected issuer"},
+		{name: "wrong audience", token: sign(a, func(c *claims) { c.Audience = "other" }), wantErr: "unexpected audience"},
+		{name: "tampered claims", token: tampered(), wantErr: "invalid signature"},
+		{name: "other key", token: sign(other, func(*claims) {}), wantErr: "unknown key"},
+		{name: "alg none", token: unsigned("none"), wantErr: "unsupported algorithm"},
+		{name: "missing signature", token: unsigned("ES256"), wantErr: "invalid signature"},
+		{name: "invalid header", token: "e30x." + parts[1] + "." + parts[2], wantErr: "invalid header"},
+		{name: "unknown static token", token: "secret", wantErr: "unknown token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := a.Authenticate(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+				if principal.Subject != "bob" || len(principal.Tenants) != 1 || principal.Roles[0] != "viewer" {
+					t.Fatalf("Authenticate() = %+v", principal)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIssueExpires(t *testing.T) {
+	a, clk := newAuthenticator(t, Config{})
+	token, expiresAt, err := a.Issue(Principal{Subject: "bob", Tenants: []string{"t1"}, Roles: []string{"viewer"}}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := clk.Now().Add(time.Minute); !expiresAt.Equal(want) {
+		t.Errorf("expiresAt = %s, want %s", expiresAt, want)
+	}
+	if _, err := a.Authenticate(token); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	clk.Advance(time.Minute)
+	if _, err := a.Authenticate(token); err == nil {
+		t.Fatal("Authenticate() of an expired token succeeded")
+	}
+}
+
+func TestAuthenticateStaticToken(t *testing.T) {
+	a, _ := newAuthenticator(t, Config{Tokens: []Token{{Token: "t1-admin", Principal: Principal{Subject: "alice"}}}})
+	if principal, err := a.Authenticate("t1-admin"); err != nil || principal.Subject != "alice" {
+		t.Errorf("Authenticate() = %+v, %v", principal, err)
+	}
+	if _, err := a.Authenticate(""); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("Authenticate(\"\") = %v, want %v", err, ErrMissingToken)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	a, _ := newAuthenticator(t, Config{Roles: map[string][]string{"compute-operator": {"seca.compute:*", "*:read"}}})
+
+	tests := []struct {
+		name      string
+		principal Principal
+		tenant    string
+		provider  string
+		verb      string
+		wantErr   bool
+	}{
+		{name: "viewer reads", principal: Principal{Tenants: []string{"t1"}, Roles: []string{"viewer"}}, tenant: "t1", provider: "seca.compute", verb: "read"},
+		{name: "viewer writes", principal: Principal{Tenants: []string{"t1"}, Roles: []string{"viewer"}}, tenant: "t1", provider: "seca.compute", verb: "write", wantErr: true},
+		{name: "other tenant", principal: Principal{Tenants: []string{"t1"}, Roles: []string{"editor"}}, tenant: "t2", provider: "seca.compute", verb: "read", wantErr: true},
+		{name: "all tenants", principal: Principal{Tenants: []string{"*"}, Roles: []string{"editor"}}, tenant: "t2", provider: "seca.compute", verb: "write"},
+		{name: "custom role", principal: Principal{Tenants: []string{"*"}, Roles: []string{"compute-operator"}}, tenant: "t1", provider: "seca.compute", verb: "write"},
+		{name: "custom role elsewhere", principal: Principal{Tenants: []string{"*"}, Roles: []string{"compute-operator"}}, tenant: "t1", provider: "seca.storage", verb: "write", wantErr: true},
+		{name: "unknown role", principal: Principal{Tenants: []string{"*"}, Roles: []string{"root"}}, tenant: "t1", provider: "seca.compute", verb: "read", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Authorize(tt.principal, tt.tenant, tt.provider, tt.verb)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: "tokens:\n  - {token: root, roles: [admin]}\nroles:\n  ops: [\"seca.compute:*\"]\n"},
+		{name: "empty token", data: "tokens:\n  - {subject: bob}\n", wantErr: true},
+		{name: "permission without verb", data: "roles:\n  ops: [seca.compute]\n", wantErr: true},
+		{name: "invalid pattern", data: "roles:\n  ops: [\"[:read\"]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
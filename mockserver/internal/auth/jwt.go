@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// claims of the JWTs issued by the mockserver, tenants and roles are custom
+// claims.
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Audience  string   `json:"aud,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	Tenants   []string `json:"tenants"`
+	Roles     []string `json:"roles"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// JWK is the public signing key in the format of a JSON web key set.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Keys returns the key set to verify issued JWTs with.
+func (a *Authenticator) Keys() []JWK {
+	x, y := coordinates(&a.key.PublicKey)
+	return []JWK{{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		X:         x,
+		Y:         y,
+		KeyID:     a.keyID,
+		Use:       "sig",
+		Algorithm: "ES256",
+	}}
+}
+
+func (a *Authenticator) sign(c claims) (string, error) {
+	head, err := json.Marshal(header{Algorithm: "ES256", Type: "JWT", KeyID: a.keyID})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	signed := encoding.EncodeToString(head) + "." + encoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + encoding.EncodeToString(signature), nil
+}
+
+func (a *Authenticator) verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	var head header
+	if err := decodePart(parts[0], &head); err != nil {
+		return Principal{}, fmt.Errorf("invalid header: %w", err)
+	}
+	if head.Algorithm != "ES256" {
+		return Principal{}, fmt.Errorf("unsupported algorithm %q", head.Algorithm)
+	}
+	if head.KeyID != "" && head.KeyID != a.keyID {
+		return Principal{}, fmt.Errorf("unknown key %q", head.KeyID)
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return Principal{}, errors.New("invalid signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&a.key.PublicKey, digest[:], r, s) {
+		return Principal{}, errors.New("invalid signature")
+	}
+
+	var c claims
+	if err := decodePart(parts[1], &c); err != nil {
+		return Principal{}, fmt.Errorf("invalid claims: %w", err)
+	}
+	now := a.clock.Now().Unix()
+	switch {
+	case c.Issuer != a.config.Issuer:
+		return Principal{}, fmt.Errorf("unexpected issuer %q", c.Issuer)
+	case a.config.Audience != "" && c.Audience != a.config.Audience:
+		return Principal{}, fmt.Errorf("unexpected audience %q", c.Audience)
+	case c.ExpiresAt <= now:
+		return Principal{}, errors.New("token expired")
+	case c.NotBefore > now:
+		return Principal{}, errors.New("token not yet valid")
+	}
+	return Principal{Subject: c.Subject, Tenants: c.Tenants, Roles: c.Roles}, nil
+}
+
+func decodePart(part string, into any) error {
+	data, err := encoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
+
+func coordinates(key *ecdsa.PublicKey) (string, string) {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return encoding.EncodeToString(x), encoding.EncodeToString(y)
+}
+
+// keyID is the SHA-256 of the public key coordinates.
+func keyID(key *ecdsa.PublicKey) string {
+	x, y := coordinates(key)
+	digest := sha256.Sum256([]byte(x + "." + y))
+	return encoding.EncodeToString(digest[:8])
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSPath serves the key set of the issued JWTs.
+const JWKSPath = "/.well-known/jwks.json"
+
+// Middleware rejects requests without a valid bearer token with 401 and
+// requests outside the tenants or roles of the caller with 403. Admin
+// endpoints need the admin role, only the key set is public.
+func Middleware(authenticator *Authenticator, adminPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestPath := c.Request.URL.Path
+		if requestPath == JWKSPath {
+			c.Next()
+			return
+		}
+
+		principal, err := authenticator.Authenticate(bearerToken(c.Request))
+		if err != nil {
+			challenge := `Bearer realm="mockserver"`
+			if !errors.Is(err, ErrMissingToken) {
+				challenge += `, error="invalid_token"`
+			}
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		if requestPath == adminPrefix || strings.HasPrefix(requestPath, adminPrefix+"/") {
+			if !slices.Contains(principal.Roles, AdminRole) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": principal.name() + " is not allowed to use the admin endpoints"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		verb := "write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			verb = "read"
+		}
+		if err := authenticator.Authorize(principal, c.Param("tenant"), provider(requestPath), verb); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// KeySet responds with the JSON web key set of the authenticator.
+func KeySet(authenticator *Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keys": authenticator.Keys()})
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// provider returns the provider of a request path, e.g. seca.compute for
+// /providers/seca.compute/v1/tenants/t1/skus.
+func provider(requestPath string) string {
+	rest, ok := strings.CutPrefix(requestPath, "/providers/")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a, _ := newAuthenticator(t, Config{Tokens: []Token{
+		{Token: "root", Principal: Principal{Subject: "ci", Roles: []string{AdminRole}}},
+		{Token: "editor", Principal: Principal{Subject: "alice", Tenants: []string{"*"}, Roles: []string{"editor"}}},
+		{Token: "viewer", Principal: Principal{Subject: "bob", Tenants: []string{"t1"}, Roles: []string{"viewer"}}},
+	}})
+	jwt, _, err := a.Issue(Principal{Subject: "carol", Tenants: []string{"t1"}, Roles: []string{"editor"}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(a, "/admin"))
+	router.GET(JWKSPath, KeySet(a))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/admin/tokens", ok)
+	router.GET("/administrators", ok)
+	router.GET("/providers/seca.compute/v1/tenants/:tenant/instances", ok)
+	router.PUT("/providers/seca.compute/v1/tenants/:tenant/instances/:name", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{name: "key set is public", method: http.MethodGet, path: JWKSPath, want: http.StatusOK},
+		{name: "missing token", method: http.MethodGet, path: "/providers/seca.compute/v1/tenants/t1/instances", want: http.StatusUnauthorized},
+		{name: "invalid token", method: http.MethodGet, path: "/providers/seca.compute/v1/tenants/t1/instances", token: "nope", want: http.StatusUnauthorized},
+		{name: "viewer reads", method: http.MethodGet, path: "/providers/seca.compute/v1/tenants/t1/instances", token: "viewer", want: http.StatusOK},
+		{name: "viewer writes", method: http.MethodPut, path: "/providers/seca.compute/v1/tenants/t1/instances/vm1", token: "viewer", want: http.StatusForbidden},
+		{name: "jwt in other tenant", method: http.MethodGet, path: "/providers/seca.compute/v1/tenants/t2/instances", token: jwt, want: http.StatusForbidden},
+		{name: "jwt writes", method: http.MethodPut, path: "/providers/seca.compute/v1/tenants/t1/instances/vm1", token: jwt, want: http.StatusOK},
+		{name: "admin without token", method: http.MethodPost, path: "/admin/tokens", want: http.StatusUnauthorized},
+		{name: "admin as editor", method: http.MethodPost, path: "/admin/tokens", token: "editor", want: http.StatusForbidden},
+		{name: "admin as admin", method: http.MethodPost, path: "/admin/tokens", token: "root", want: http.StatusOK},
+		{name: "admin prefix only matches the admin path", method: http.MethodGet, path: "/administrators", token: "root", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+		})
+	}
+}
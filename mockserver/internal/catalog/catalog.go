@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// InstanceSKU is a compute SKU offered by the mockserver.
+type InstanceSKU struct {
+	Name string `yaml:"name"`
+	// Tier is the SKU family, e.g. D for general purpose.
+	Tier         string `yaml:"tier"`
+	VCPU         int    `yaml:"vCPU"`
+	RAM          int    `yaml:"ram"`
+	Architecture string `yaml:"architecture"`
+	// Labels are added to the labels derived from the fields above.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// DefaultInstanceSKUs are general purpose (D), compute optimized (C), memory
+// optimized (M) and ARM (A) SKUs. RAM is in GB.
+var DefaultInstanceSKUs = []InstanceSKU{
+	{Name: "seca.d2", Tier: "D", VCPU: 2, RAM: 8, Architecture: "amd64"},
+	{Name: "seca.d4", Tier: "D", VCPU: 4, RAM: 16, Architecture: "amd64"},
+	{Name: "seca.d8", Tier: "D", VCPU: 8, RAM: 32, Architecture: "amd64"},
+	{Name: "seca.d16", Tier: "D", VCPU: 16, RAM: 64, Architecture: "amd64"},
+	{Name: "seca.c2", Tier: "C", VCPU: 2, RAM: 4, Architecture: "amd64"},
+	{Name: "seca.c4", Tier: "C", VCPU: 4, RAM: 8, Architecture: "amd64"},
+	{Name: "seca.c8", Tier: "C", VCPU: 8, RAM: 16, Architecture: "amd64"},
+	{Name: "seca.c16", Tier: "C", VCPU: 16, RAM: 32, Architecture: "amd64"},
+	{Name: "seca.m2", Tier: "M", VCPU: 2, RAM: 16, Architecture: "amd64"},
+	{Name: "seca.m4", Tier: "M", VCPU: 4, RAM: 32, Architecture: "amd64"},
+	{Name: "seca.m8", Tier: "M", VCPU: 8, RAM: 64, Architecture: "amd64"},
+	{Name: "seca.a2", Tier: "A", VCPU: 2, RAM: 8, Architecture: "arm64"},
+	{Name: "seca.a4", Tier: "A", VCPU: 4, RAM: 16, Architecture: "arm64"},
+	{Name: "seca.a8", Tier: "A", VCPU: 8, RAM: 32, Architecture: "arm64"},
+}
+
+// ReadInstanceSKUs reads a YAML or JSON list of instance SKUs. It replaces
+// the default SKUs.
+func ReadInstanceSKUs(path string) ([]InstanceSKU, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var skus []InstanceSKU
+	if err := yaml.Unmarshal(data, &skus); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	names := map[string]bool{}
+	for i := range skus {
+		sku := &skus[i]
+		switch {
+		case sku.Name == "":
+			return nil, fmt.Errorf("sku %d: name is required", i)
+		case names[sku.Name]:
+			return nil, fmt.Errorf("sku %s: duplicate name", sku.Name)
+		case sku.VCPU <= 0 || sku.RAM <= 0:
+			return nil, fmt.Errorf("sku %s: vCPU and ram must be positive", sku.Name)
+		}
+		names[sku.Name] = true
+		if sku.Architecture == "" {
+			sku.Architecture = "amd64"
+		}
+	}
+	return skus, nil
+}
+
+// PublicImage is an image every tenant can boot from.
+type PublicImage struct {
+	Name         string
+	OS           string
+	Version      string
+	Architecture string
+	SizeGB       int
+}
+
+var PublicImages = []PublicImage{
+	{Name: "ubuntu-24.04-amd64", OS: "ubuntu", Version: "24.04", Architecture: "amd64", SizeGB: 10},
+	{Name: "ubuntu-24.04-arm64", OS: "ubuntu", Version: "24.04", Architecture: "arm64", SizeGB: 10},
+	{Name: "ubuntu-22.04-amd64", OS: "ubuntu", Version: "22.04", Architecture: "amd64", SizeGB: 10},
+	{Name: "debian-12-amd64", OS: "debian", Version: "12", Architecture: "amd64", SizeGB: 8},
+	{Name: "debian-12-arm64", OS: "debian", Version: "12", Architecture: "arm64", SizeGB: 8},
+	{Name: "rocky-9-amd64", OS: "rocky", Version: "9", Architecture: "amd64", SizeGB: 12},
+	{Name: "windows-server-2022-amd64", OS: "windows", Version: "2022", Architecture: "amd64", SizeGB: 40},
+}
@@ -0,0 +1,92 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the mockserver. State transitions are
+// scheduled through it so tests can control them with a Manual clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f after d has elapsed. Real calls f in its own
+	// goroutine, Manual calls it from Advance.
+	AfterFunc(d time.Duration, f func())
+}
+
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+// Manual only moves forward when Advance is called.
+type Manual struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []timer
+}
+
+type timer struct {
+	at  time.Time
+	seq int
+	f   func()
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now.UTC()}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Manual) AfterFunc(d time.Duration, f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	m.timers = append(m.timers, timer{at: m.now.Add(d), seq: m.seq, f: f})
+	sort.Slice(m.timers, func(i, j int) bool {
+		if m.timers[i].at.Equal(m.timers[j].at) {
+			return m.timers[i].seq < m.timers[j].seq
+		}
+		return m.timers[i].at.Before(m.timers[j].at)
+	})
+}
+
+// Advance moves the clock forward by d and runs all timers that became due
+// on the calling goroutine, in order and with the clock set to their due
+// time. Timers scheduled by a running timer fire in the same call if they
+// are due.
+func (m *Manual) Advance(d time.Duration) time.Time {
+	m.mu.Lock()
+	target := m.now.Add(d)
+	for len(m.timers) > 0 && !m.timers[0].at.After(target) {
+		next := m.timers[0]
+		m.timers = m.timers[1:]
+		if next.at.After(m.now) {
+			m.now = next.at
+		}
+		m.mu.Unlock()
+		next.f()
+		m.mu.Lock()
+	}
+	m.now = target
+	m.mu.Unlock()
+	return target
+}
+
+// Pending returns the number of timers that have not fired yet.
+func (m *Manual) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.timers)
+}
@@ -0,0 +1,55 @@
+package conditional
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check evaluates the If-Match and If-None-Match headers of a write request
+// against the currently stored resource version. It writes a 412 response and
+// returns false if a precondition does not hold.
+func Check(c *gin.Context, exists bool, version int64) bool {
+	if ifMatch := strings.TrimSpace(c.GetHeader("If-Match")); ifMatch != "" {
+		if !exists {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource does not exist"})
+			return false
+		}
+		if !matchesETag(ifMatch, version) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": fmt.Sprintf("resource version mismatch, current version is %d", version)})
+			return false
+		}
+	}
+
+	if ifNoneMatch := strings.TrimSpace(c.GetHeader("If-None-Match")); ifNoneMatch != "" && exists {
+		if ifNoneMatch == "*" || matchesETag(ifNoneMatch, version) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource already exists"})
+			return false
+		}
+	}
+
+	return true
+}
+
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+func matchesETag(header string, version int64) bool {
+	current := strconv.FormatInt(version, 10)
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		tag = strings.Trim(tag, `"`)
+		if tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package env
+
+import (
+	"cape-project.eu/mockserver/internal/auth"
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/internal/faults"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/state"
+	"cape-project.eu/mockserver/internal/timing"
+)
+
+// Env is shared by all provider servers of a mockserver.
+type Env struct {
+	Registry *state.Registry
+	Clock    clock.Clock
+	Timings  timing.Config
+	Faults   *faults.Injector
+	// Integrity lets servers check references to resources of other servers.
+	Integrity *integrity.Registry
+	// Auth checks the bearer tokens of requests, nil if anonymous requests
+	// are allowed.
+	Auth *auth.Authenticator
+	// InstanceSKUs replaces the default compute SKU catalog if set.
+	InstanceSKUs []catalog.InstanceSKU
+}
@@ -0,0 +1,329 @@
+package faults
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/models"
+	"go.yaml.in/yaml/v4"
+)
+
+const (
+	// StateStuck keeps a resource in its transitional state.
+	StateStuck = "stuck"
+	// StateError moves a resource to error instead of active.
+	StateError = "error"
+)
+
+// Rule injects a fault into the requests or state transitions it matches.
+// Empty match fields match everything.
+type Rule struct {
+	ID string `json:"id,omitempty" yaml:"id"`
+
+	// Method is the HTTP method, e.g. PUT.
+	Method string `json:"method,omitempty" yaml:"method"`
+	// Path is a path.Match pattern for the request path, e.g.
+	// /providers/seca.compute/v1/tenants/*/workspaces/*/instances/*.
+	Path string `json:"path,omitempty" yaml:"path"`
+	// Name is a path.Match pattern for the resource name.
+	Name string `json:"name,omitempty" yaml:"name"`
+	// Kind is the resource kind for state faults, e.g. instance.
+	Kind string `json:"kind,omitempty" yaml:"kind"`
+
+	// Probability to apply the rule to a matching request, 1 if unset.
+	Probability float64 `json:"probability,omitempty" yaml:"probability"`
+	// Times limits how often the rule is applied, unlimited if 0.
+	Times int `json:"times,omitempty" yaml:"times"`
+
+	// Status responds with the status code, or a random one of Statuses.
+	Status   int    `json:"status,omitempty" yaml:"status"`
+	Statuses []int  `json:"statuses,omitempty" yaml:"statuses"`
+	Message  string `json:"message,omitempty" yaml:"message"`
+	// Latency delays the request by Latency, or a random duration between
+	// Latency and MaxLatency.
+	Latency    string `json:"latency,omitempty" yaml:"latency"`
+	MaxLatency string `json:"maxLatency,omitempty" yaml:"maxLatency"`
+	// Drop closes the connection without a response.
+	Drop bool `json:"drop,omitempty" yaml:"drop"`
+	// RateLimit responds with 429 once more than Requests requests matched
+	// within Window.
+	RateLimit *RateLimit `json:"rateLimit,omitempty" yaml:"rateLimit"`
+	// State is StateStuck or StateError.
+	State string `json:"state,omitempty" yaml:"state"`
+
+	latency    time.Duration
+	maxLatency time.Duration
+	applied    int
+	window     time.Time
+	requests   int
+}
+
+type RateLimit struct {
+	Requests int    `json:"requests" yaml:"requests"`
+	Window   string `json:"window" yaml:"window"`
+
+	window time.Duration
+}
+
+func (r *Rule) validate() error {
+	var err error
+	if r.Latency != "" {
+		if r.latency, err = time.ParseDuration(r.Latency); err != nil {
+			return fmt.Errorf("invalid latency: %w", err)
+		}
+	}
+	if r.MaxLatency != "" {
+		if r.maxLatency, err = time.ParseDuration(r.MaxLatency); err != nil {
+			return fmt.Errorf("invalid maxLatency: %w", err)
+		}
+		if r.maxLatency < r.latency {
+			return fmt.Errorf("maxLatency must not be less than latency")
+		}
+	}
+	for _, pattern := range []string{r.Path, r.Name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	for _, status := range append(slices.Clone(r.Statuses), r.Status) {
+		if status != 0 && (status < 100 || status > 599) {
+			return fmt.Errorf("invalid status %d", status)
+		}
+	}
+	if r.RateLimit != nil {
+		if r.RateLimit.Requests < 0 {
+			return fmt.Errorf("rateLimit.requests must not be negative")
+		}
+		if r.RateLimit.window, err = time.ParseDuration(r.RateLimit.Window); err != nil || r.RateLimit.window <= 0 {
+			return fmt.Errorf("rateLimit.window must be a positive duration")
+		}
+	}
+	switch r.State {
+	case "", StateStuck, StateError:
+	default:
+		return fmt.Errorf("invalid state %q, expected %s or %s", r.State, StateStuck, StateError)
+	}
+	if r.State != "" && (r.Status != 0 || len(r.Statuses) > 0 || r.Latency != "" || r.Drop || r.RateLimit != nil) {
+		return fmt.Errorf("state faults cannot be combined with request faults")
+	}
+	return nil
+}
+
+func (r *Rule) matchesRequest(method, requestPath, name string) bool {
+	if r.State != "" {
+		return false
+	}
+	if r.Method != "" && r.Method != method {
+		return false
+	}
+	return match(r.Path, requestPath) && match(r.Name, name)
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// Injector holds the active fault rules.
+type Injector struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	random *rand.Rand
+	rules  []*Rule
+	nextID int
+}
+
+func NewInjector(clk clock.Clock) *Injector {
+	return &Injector{clock: clk, random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// Add validates and adds rules. Rules without ID get a generated one.
+func (i *Injector) Add(rules ...Rule) ([]Rule, error) {
+	added := make([]*Rule, 0, len(rules))
+	for n := range rules {
+		rule := rules[n]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", n, err)
+		}
+		added = append(added, &rule)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, rule := range added {
+		if rule.ID == "" {
+			i.nextID++
+			rule.ID = strconv.Itoa(i.nextID)
+		}
+		i.rules = slices.DeleteFunc(i.rules, func(existing *Rule) bool {
+			return existing.ID == rule.ID
+		})
+		i.rules = append(i.rules, rule)
+	}
+	return derefRules(added), nil
+}
+
+func (i *Injector) Rules() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return derefRules(i.rules)
+}
+
+// Remove deletes the rule with id and reports whether it existed.
+func (i *Injector) Remove(id string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	n := len(i.rules)
+	i.rules = slices.DeleteFunc(i.rules, func(rule *Rule) bool {
+		return rule.ID == id
+	})
+	return len(i.rules) != n
+}
+
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+}
+
+// Fault is what happens to a request.
+type Fault struct {
+	Latency time.Duration
+	Drop    bool
+	// Status is 0 if the request is handled normally.
+	Status     int
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Request returns the combined faults of all rules matching the request.
+func (i *Injector) Request(method, requestPath, name string) Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var fault Fault
+	now := i.clock.Now()
+	for _, rule := range i.rules {
+		if !rule.matchesRequest(method, requestPath, name) {
+			continue
+		}
+		if rule.RateLimit != nil {
+			if retryAfter, limited := rule.limit(now); limited && fault.Status == 0 {
+				fault.Status = 429
+				fault.Message = "rate limit exceeded"
+				fault.RetryAfter = retryAfter
+			}
+			continue
+		}
+		if !rule.apply(i.random) {
+			continue
+		}
+		fault.Latency += rule.randomLatency(i.random)
+		fault.Drop = fault.Drop || rule.Drop
+		if fault.Status == 0 {
+			fault.Status = rule.randomStatus(i.random)
+			fault.Message = rule.Message
+		}
+	}
+	return fault
+}
+
+// Transition returns the state a resource should move to instead of target,
+// and false if the transition should not happen at all.
+func (i *Injector) Transition(kind, name string, target models.ResourceState) (models.ResourceState, bool) {
+	if i == nil || target != models.ResourceStateActive {
+		return target, true
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, rule := range i.rules {
+		if rule.State == "" || (rule.Kind != "" && rule.Kind != kind) || !match(rule.Name, name) {
+			continue
+		}
+		if !rule.apply(i.random) {
+			continue
+		}
+		if rule.State == StateStuck {
+			return target, false
+		}
+		return models.ResourceStateError, true
+	}
+	return target, true
+}
+
+// apply decides whether the rule fires and counts it.
+func (r *Rule) apply(random *rand.Rand) bool {
+	if r.Times > 0 && r.applied >= r.Times {
+		return false
+	}
+	if r.Probability > 0 && random.Float64() >= r.Probability {
+		return false
+	}
+	r.applied++
+	return true
+}
+
+func (r *Rule) limit(now time.Time) (time.Duration, bool) {
+	if r.window.IsZero() || !now.Before(r.window.Add(r.RateLimit.window)) {
+		r.window = now
+		r.requests = 0
+	}
+	r.requests++
+	if r.requests <= r.RateLimit.Requests {
+		return 0, false
+	}
+	return r.window.Add(r.RateLimit.window).Sub(now), true
+}
+
+func (r *Rule) randomLatency(random *rand.Rand) time.Duration {
+	if r.maxLatency <= r.latency {
+		return r.latency
+	}
+	return r.latency + time.Duration(random.Int64N(int64(r.maxLatency-r.latency+1)))
+}
+
+func (r *Rule) randomStatus(random *rand.Rand) int {
+	if len(r.Statuses) > 0 {
+		return r.Statuses[random.IntN(len(r.Statuses))]
+	}
+	return r.Status
+}
+
+func derefRules(rules []*Rule) []Rule {
+	result := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, *rule)
+	}
+	return result
+}
+
+// ParseRules reads a YAML or JSON list of rules.
+func ParseRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse fault rules: %w", err)
+	}
+	return rules, nil
+}
+
+func ReadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
@@ -0,0 +1,232 @@
+package faults
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"testing"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/models"
+)
+
+// newInjector returns an injector with a seeded random source on a manual
+// clock.
+func newInjector(t *testing.T, rules ...Rule) (*Injector, *clock.Manual) {
+	t.Helper()
+	clk := clock.NewManual(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	i := NewInjector(clk)
+	i.random = rand.New(rand.NewPCG(1, 2))
+	if _, err := i.Add(rules...); err != nil {
+		t.Fatal(err)
+	}
+	return i, clk
+}
+
+func TestRateLimit(t *testing.T) {
+	i, clk := newInjector(t, Rule{Method: http.MethodGet, RateLimit: &RateLimit{Requests: 2, Window: "1s"}})
+
+	steps := []struct {
+		advance        time.Duration
+		method         string
+		wantStatus     int
+		wantRetryAfter time.Duration
+	}{
+		{wantStatus: 0},
+		{advance: 100 * time.Millisecond, wantStatus: 0},
+		{advance: 100 * time.Millisecond, wantStatus: http.StatusTooManyRequests, wantRetryAfter: 800 * time.Millisecond},
+		{method: http.MethodPut, wantStatus: 0},
+		{advance: 500 * time.Millisecond, wantStatus: http.StatusTooManyRequests, wantRetryAfter: 300 * time.Millisecond},
+		// A new window starts once the old one has passed.
+		{advance: 300 * time.Millisecond, wantStatus: 0},
+		{wantStatus: 0},
+		{wantStatus: http.StatusTooManyRequests, wantRetryAfter: time.Second},
+	}
+	for n, step := range steps {
+		clk.Advance(step.advance)
+		method := step.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		fault := i.Request(method, "/providers/seca.compute/v1/tenants/t1/skus", "")
+		if fault.Status != step.wantStatus || fault.RetryAfter != step.wantRetryAfter {
+			t.Fatalf("request %d: fault = %+v, want status %d retry after %s", n, fault, step.wantStatus, step.wantRetryAfter)
+		}
+	}
+}
+
+func TestRateLimitWithoutRequests(t *testing.T) {
+	i, _ := newInjector(t, Rule{RateLimit: &RateLimit{Requests: 0, Window: "1m"}})
+	if fault := i.Request(http.MethodGet, "/", ""); fault.Status != http.StatusTooManyRequests || fault.RetryAfter != time.Minute {
+		t.Fatalf("fault = %+v, want 429 retry after 1m", fault)
+	}
+}
+
+func TestProbability(t *testing.T) {
+	count := func() (hits int, sequence []bool) {
+		i, _ := newInjector(t, Rule{Status: http.StatusServiceUnavailable, Probability: 0.25})
+		for range 1000 {
+			hit := i.Request(http.MethodGet, "/", "").Status == http.StatusServiceUnavailable
+			if hit {
+				hits++
+			}
+			sequence = append(sequence, hit)
+		}
+		return hits, sequence
+	}
+	hits, first := count()
+	if hits < 200 || hits > 300 {
+		t.Errorf("rule applied %d of 1000 times, want about 250", hits)
+	}
+	// The same seed applies the rule to the same requests.
+	_, second := count()
+	for n := range first {
+		if first[n] != second[n] {
+			t.Fatalf("request %d differs between runs with the same seed", n)
+		}
+	}
+}
+
+func TestTimes(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        Rule
+		requests    int
+		wantApplied int
+	}{
+		{name: "limited", rule: Rule{Status: 500, Times: 2}, requests: 5, wantApplied: 2},
+		{name: "unlimited", rule: Rule{Status: 500}, requests: 5, wantApplied: 5},
+		{name: "counts only applied requests", rule: Rule{Status: 500, Times: 3, Probability: 0.5}, requests: 100, wantApplied: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, _ := newInjector(t, tt.rule)
+			applied := 0
+			for range tt.requests {
+				if i.Request(http.MethodGet, "/", "").Status != 0 {
+					applied++
+				}
+			}
+			if applied != tt.wantApplied {
+				t.Fatalf("applied %d times, want %d", applied, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestRequestMatching(t *testing.T) {
+	i, _ := newInjector(t,
+		Rule{Method: http.MethodPut, Path: "/providers/seca.compute/v1/tenants/*/workspaces/*/instances/*", Name: "vm-*", Status: 503, Message: "down"},
+		Rule{Path: "/providers/seca.storage/*", Status: 500},
+		Rule{Path: "/providers/seca.storage/*", Status: 502, Latency: "10ms", Drop: true},
+	)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		res    string
+		want   Fault
+	}{
+		{name: "match", method: http.MethodPut, path: "/providers/seca.compute/v1/tenants/t1/workspaces/w1/instances/vm-1", res: "vm-1", want: Fault{Status: 503, Message: "down"}},
+		{name: "other method", method: http.MethodGet, path: "/providers/seca.compute/v1/tenants/t1/workspaces/w1/instances/vm-1", res: "vm-1"},
+		{name: "other name", method: http.MethodPut, path: "/providers/seca.compute/v1/tenants/t1/workspaces/w1/instances/db-1", res: "db-1"},
+		{name: "first status wins, faults add up", method: http.MethodGet, path: "/providers/seca.storage/v1", want: Fault{Status: 500, Latency: 10 * time.Millisecond, Drop: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i.Request(tt.method, tt.path, tt.res); got != tt.want {
+				t.Fatalf("Request() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomStatusAndLatency(t *testing.T) {
+	i, _ := newInjector(t, Rule{Statuses: []int{500, 503}, Latency: "10ms", MaxLatency: "20ms"})
+	seen := map[int]bool{}
+	for range 100 {
+		fault := i.Request(http.MethodGet, "/", "")
+		seen[fault.Status] = true
+		if fault.Latency < 10*time.Millisecond || fault.Latency > 20*time.Millisecond {
+			t.Fatalf("latency = %s, want between 10ms and 20ms", fault.Latency)
+		}
+	}
+	if len(seen) != 2 || !seen[500] || !seen[503] {
+		t.Fatalf("statuses = %v, want 500 and 503", seen)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	i, _ := newInjector(t,
+		Rule{Kind: "instance", Name: "stuck-*", State: StateStuck},
+		Rule{Kind: "block-storage", State: StateError, Times: 1},
+	)
+	tests := []struct {
+		name   string
+		kind   string
+		res    string
+		target models.ResourceState
+		want   models.ResourceState
+		wantOK bool
+	}{
+		{name: "stuck", kind: "instance", res: "stuck-1", target: models.ResourceStateActive, want: models.ResourceStateActive},
+		{name: "stuck only blocks active", kind: "instance", res: "stuck-1", target: models.ResourceStateCreating, want: models.ResourceStateCreating, wantOK: true},
+		{name: "other name", kind: "instance", res: "vm-1", target: models.ResourceStateActive, want: models.ResourceStateActive, wantOK: true},
+		{name: "error", kind: "block-storage", res: "bs", target: models.ResourceStateActive, want: models.ResourceStateError, wantOK: true},
+		{name: "error only once", kind: "block-storage", res: "bs", target: models.ResourceStateActive, want: models.ResourceStateActive, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := i.Transition(tt.kind, tt.res, tt.target)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("Transition() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+	// State rules never affect requests.
+	if fault := i.Request(http.MethodPut, "/", "stuck-1"); fault != (Fault{}) {
+		t.Errorf("Request() = %+v, want no fault", fault)
+	}
+}
+
+func TestAddValidates(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "latency", rule: Rule{Latency: "soon"}},
+		{name: "max latency below latency", rule: Rule{Latency: "2s", MaxLatency: "1s"}},
+		{name: "pattern", rule: Rule{Path: "["}},
+		{name: "probability", rule: Rule{Probability: 1.5}},
+		{name: "status", rule: Rule{Statuses: []int{42}}},
+		{name: "negative requests", rule: Rule{RateLimit: &RateLimit{Requests: -1, Window: "1s"}}},
+		{name: "window", rule: Rule{RateLimit: &RateLimit{Requests: 1, Window: "0s"}}},
+		{name: "state", rule: Rule{State: "broken"}},
+		{name: "state and status", rule: Rule{State: StateStuck, Status: 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, _ := newInjector(t)
+			if _, err := i.Add(tt.rule); err == nil {
+				t.Fatal("Add() succeeded")
+			}
+			if rules := i.Rules(); len(rules) != 0 {
+				t.Fatalf("Rules() = %+v after a failed Add()", rules)
+			}
+		})
+	}
+}
+
+func TestAddReplacesByID(t *testing.T) {
+	i, _ := newInjector(t, Rule{Status: 500}, Rule{ID: "slow", Latency: "1s"})
+	if _, err := i.Add(Rule{ID: "slow", Latency: "2s"}); err != nil {
+		t.Fatal(err)
+	}
+	rules := i.Rules()
+	if len(rules) != 2 || rules[0].ID != "1" || rules[1].Latency != "2s" {
+		t.Fatalf("Rules() = %+v", rules)
+	}
+	if !i.Remove("1") || i.Remove("1") {
+		t.Fatal("Remove() did not remove the rule once")
+	}
+}
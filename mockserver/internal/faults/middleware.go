@@ -0,0 +1,63 @@
+package faults
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware applies the request faults of injector. Admin endpoints are
+// never affected.
+func Middleware(injector *Injector, adminPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestPath := c.Request.URL.Path
+		if strings.HasPrefix(requestPath, adminPrefix) {
+			c.Next()
+			return
+		}
+
+		fault := injector.Request(c.Request.Method, requestPath, c.Param("name"))
+		if fault.Latency > 0 {
+			timer := time.NewTimer(fault.Latency)
+			select {
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			case <-timer.C:
+			}
+		}
+		if fault.Drop {
+			drop(c)
+			return
+		}
+		if fault.Status == 0 {
+			c.Next()
+			return
+		}
+
+		if fault.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(fault.RetryAfter.Seconds()))))
+		}
+		message := fault.Message
+		if message == "" {
+			message = "injected fault: " + http.StatusText(fault.Status)
+		}
+		c.AbortWithStatusJSON(fault.Status, gin.H{"error": message})
+	}
+}
+
+// drop closes the connection without writing a response.
+func drop(c *gin.Context) {
+	c.Abort()
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	_ = conn.Close()
+}
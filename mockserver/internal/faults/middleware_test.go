@@ -0,0 +1,64 @@
+package faults
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	i, _ := newInjector(t,
+		Rule{Path: "/providers/*/fail", Status: http.StatusServiceUnavailable, Message: "maintenance"},
+		Rule{Path: "/providers/*/teapot", Status: http.StatusTeapot},
+		Rule{Path: "/providers/*/limited", RateLimit: &RateLimit{Requests: 0, Window: "1500ms"}},
+		Rule{Path: "/providers/*/drop", Drop: true},
+		Rule{Path: "/admin/*", Status: http.StatusInternalServerError},
+	)
+	router := gin.New()
+	router.Use(Middleware(i, "/admin"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/providers/:provider/:name", ok)
+	router.GET("/admin/:name", ok)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		want           int
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{name: "no fault", path: "/providers/seca.compute/ok", want: http.StatusOK},
+		{name: "status", path: "/providers/seca.compute/fail", want: http.StatusServiceUnavailable, wantBody: `{"error":"maintenance"}`},
+		{name: "default message", path: "/providers/seca.compute/teapot", want: http.StatusTeapot, wantBody: `{"error":"injected fault: I'm a teapot"}`},
+		{name: "rate limit", path: "/providers/seca.compute/limited", want: http.StatusTooManyRequests, wantRetryAfter: "2"},
+		{name: "admin is never affected", path: "/admin/fail", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		res, err := server.Client().Get(server.URL + "/providers/seca.compute/drop")
+		if err == nil {
+			_ = res.Body.Close()
+			t.Fatalf("request got %d, want a closed connection", res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package integrity
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Ref identifies a resource across the provider servers. Workspace is empty
+// for tenant level resources.
+type Ref struct {
+	Kind      string
+	Tenant    string
+	Workspace string
+	Name      string
+}
+
+func (r Ref) String() string {
+	if r.Workspace == "" {
+		return fmt.Sprintf("%s %s/%s", r.Kind, r.Tenant, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s/%s", r.Kind, r.Tenant, r.Workspace, r.Name)
+}
+
+// Owner is implemented by the provider servers. Servers must not hold their
+// own lock while calling the registry, as it calls back into all owners.
+type Owner interface {
+	// Exists reports whether the resource ref, of a kind the owner was
+	// registered for, exists and is not being deleted.
+	Exists(ref Ref) bool
+	// Resource returns the resource ref, of a kind the owner was registered
+	// for.
+	Resource(ref Ref) (any, bool)
+	// ReferencesTo returns the resources of the owner that reference ref,
+	// including the resources contained in a workspace. Resources that are
+	// being deleted do not count.
+	ReferencesTo(ref Ref) []Ref
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	kinds  map[string]Owner
+	owners []Owner
+	// writes serializes reference checks with the writes that rely on them.
+	writes sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{kinds: map[string]Owner{}}
+}
+
+// Register adds owner as the owner of resources of kinds.
+func (r *Registry) Register(owner Owner, kinds ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		r.kinds[kind] = owner
+	}
+	r.owners = append(r.owners, owner)
+}
+
+// Lock is held by servers from checking references until the resource is
+// written, and from checking that a resource is unreferenced until it is
+// deleting. Owners must not call it while they hold their own lock.
+func (r *Registry) Lock() {
+	if r != nil {
+		r.writes.Lock()
+	}
+}
+
+func (r *Registry) Unlock() {
+	if r != nil {
+		r.writes.Unlock()
+	}
+}
+
+// Exists reports whether ref exists. Without a registry or an owner for the
+// kind every reference is assumed to exist.
+func (r *Registry) Exists(ref Ref) bool {
+	if r == nil {
+		return true
+	}
+	r.mu.RLock()
+	owner, ok := r.kinds[ref.Kind]
+	r.mu.RUnlock()
+	return !ok || owner.Exists(ref)
+}
+
+// Resource returns the resource ref, e.g. a models.BlockStorage. It reports
+// false if the resource does not exist or no owner is registered for its kind.
+func (r *Registry) Resource(ref Ref) (any, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	owner, ok := r.kinds[ref.Kind]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return owner.Resource(ref)
+}
+
+// ReferencesTo returns all resources that reference ref.
+func (r *Registry) ReferencesTo(ref Ref) []Ref {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	owners := r.owners
+	r.mu.RUnlock()
+
+	var refs []Ref
+	for _, owner := range owners {
+		refs = append(refs, owner.ReferencesTo(ref)...)
+	}
+	return refs
+}
+
+// CheckUnreferenced returns an error listing the resources that still
+// reference ref.
+func (r *Registry) CheckUnreferenced(ref Ref) error {
+	refs := r.ReferencesTo(ref)
+	if len(refs) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(refs))
+	for _, referencing := range refs {
+		names = append(names, referencing.String())
+	}
+	return fmt.Errorf("%s is still referenced by %s", ref, strings.Join(names, ", "))
+}
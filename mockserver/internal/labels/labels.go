@@ -0,0 +1,146 @@
+package labels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a parsed label selector: comma separated requirements that all
+// have to match, e.g.
+//
+//	tier=RD*,iops>=500,env in (dev, "qa 2"),!deprecated
+//
+// Keys and unquoted values may contain * wildcards, quoted values are
+// compared literally.
+type Selector struct {
+	requirements []requirement
+}
+
+type operator string
+
+const (
+	equals         operator = "="
+	notEquals      operator = "!="
+	greater        operator = ">"
+	greaterOrEqual operator = ">="
+	less           operator = "<"
+	lessOrEqual    operator = "<="
+	in             operator = "in"
+	notIn          operator = "notin"
+	exists         operator = "exists"
+	notExists      operator = "!"
+)
+
+type requirement struct {
+	key      pattern
+	operator operator
+	values   []pattern
+	number   float64
+}
+
+// Parse parses selector, an empty selector matches all labels.
+func Parse(selector string) (Selector, error) {
+	tokens, err := tokenize(selector)
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid label selector: %w", err)
+	}
+	p := parser{tokens: tokens}
+	requirements, err := p.parse()
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid label selector: %w", err)
+	}
+	return Selector{requirements: requirements}, nil
+}
+
+// Matches reports whether labels fulfil all requirements of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	switch r.operator {
+	case notEquals, notIn, notExists:
+		// No label with a matching key may have one of the values.
+		for key, value := range labels {
+			if r.key.match(key) && r.matchesValue(value) {
+				return false
+			}
+		}
+		return true
+	default:
+		for key, value := range labels {
+			if r.key.match(key) && r.matchesValue(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (r requirement) matchesValue(value string) bool {
+	switch r.operator {
+	case exists, notExists:
+		return true
+	case greater, greaterOrEqual, less, lessOrEqual:
+		current, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		switch r.operator {
+		case greater:
+			return current > r.number
+		case greaterOrEqual:
+			return current >= r.number
+		case less:
+			return current < r.number
+		default:
+			return current <= r.number
+		}
+	default:
+		for _, pattern := range r.values {
+			if pattern.match(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// pattern matches a literal or, if it contains * wildcards, the parts
+// between the wildcards in order.
+type pattern struct {
+	literal string
+	parts   []string
+}
+
+func newPattern(value string, wildcards bool) pattern {
+	if !wildcards || !strings.Contains(value, "*") {
+		return pattern{literal: value}
+	}
+	return pattern{parts: strings.Split(value, "*")}
+}
+
+func (p pattern) match(value string) bool {
+	if p.parts == nil {
+		return value == p.literal
+	}
+	first, last := p.parts[0], p.parts[len(p.parts)-1]
+	if !strings.HasPrefix(value, first) {
+		return false
+	}
+	value = value[len(first):]
+	for _, part := range p.parts[1 : len(p.parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
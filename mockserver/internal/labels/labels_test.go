@@ -0,0 +1,116 @@
+package labels
+
+import "testing"
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{
+		"env":     "qa 2",
+		"tier":    "RD500",
+		"iops":    "500",
+		"name":    "a,b",
+		"aa":      "x",
+		"empty":   "",
+		"version": "v1.2",
+	}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "", want: true},
+		{selector: "tier=RD500", want: true},
+		{selector: "tier==RD500", want: true},
+		{selector: "tier=RD100", want: false},
+		{selector: "tier!=RD100", want: true},
+		{selector: "tier!=RD*", want: false},
+		{selector: "missing!=x", want: true},
+		{selector: "tier=RD500,iops>=500", want: true},
+		{selector: "tier=RD500,iops>500", want: false},
+		{selector: "iops<1000,iops>100", want: true},
+		{selector: "version>1", want: false},
+		{selector: "missing<1", want: false},
+
+		// Wildcards in keys and unquoted values, quoted values are literal.
+		{selector: "tier=RD*", want: true},
+		{selector: `tier="RD*"`, want: false},
+		{selector: "ti*=*500", want: true},
+		{selector: "*=RD500", want: true},
+		{selector: "t*r=R*5*0", want: true},
+
+		// The parts of a pattern must not overlap, a*a needs two a.
+		{selector: "a*a", want: true},
+		{selector: "a*a=x", want: true},
+		{selector: "tier=R*R", want: false},
+		{selector: "tier=RD5*500", want: false},
+		{selector: "tier=RD*500", want: true},
+		{selector: "tier=RD500*", want: true},
+
+		// Commas in quoted values.
+		{selector: `name="a,b"`, want: true},
+		{selector: `name in ("a,b", c)`, want: true},
+		{selector: `name in (a, b)`, want: false},
+		{selector: `env in (dev, "qa 2")`, want: true},
+		{selector: `env notin (dev, "qa 2")`, want: false},
+		{selector: "missing notin (x)", want: true},
+
+		// Trailing and empty requirements are skipped.
+		{selector: "tier=RD500,", want: true},
+		{selector: ",,tier=RD100,", want: false},
+
+		// Existence.
+		{selector: "env", want: true},
+		{selector: "missing", want: false},
+		{selector: "!missing", want: true},
+		{selector: "!env", want: false},
+		{selector: "!e*", want: false},
+		{selector: "!x*", want: true},
+		{selector: "env,!missing,tier in (RD500)", want: true},
+
+		// Empty values.
+		{selector: "empty=", want: true},
+		{selector: `empty=""`, want: true},
+		{selector: "env=", want: false},
+		{selector: "missing=", want: false},
+		{selector: "empty!=,env", want: false},
+		{selector: "missing!=", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := selector.Matches(labels); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{pattern: "a", value: "a", want: true},
+		{pattern: "a", value: "aa", want: false},
+		{pattern: "*", value: "", want: true},
+		{pattern: "a*", value: "a", want: true},
+		{pattern: "*a", value: "a", want: true},
+		{pattern: "a*a", value: "a", want: false},
+		{pattern: "a*a", value: "aa", want: true},
+		{pattern: "a*a", value: "aba", want: true},
+		{pattern: "a*a*a", value: "aa", want: false},
+		{pattern: "a*a*a", value: "aaa", want: true},
+		{pattern: "*ab*", value: "xaby", want: true},
+		{pattern: "*ab*ab", value: "xab", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.value, func(t *testing.T) {
+			if got := newPattern(tt.pattern, true).match(tt.value); got != tt.want {
+				t.Fatalf("match(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	word tokenKind = iota
+	quoted
+	symbol
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	return strconv.Quote(t.value)
+}
+
+const symbols = ",()=!<>"
+
+// tokenize splits selector into words, quoted strings and the symbols
+// , ( ) = == != < <= > >= !.
+func tokenize(selector string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(selector); {
+		switch c := selector[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			value, n, err := unquote(selector[i:])
+			if err != nil {
+				return nil, fmt.Errorf("at %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: quoted, value: value, pos: i})
+			i += n
+		case strings.IndexByte(symbols, c) >= 0:
+			n := 1
+			if i+1 < len(selector) && selector[i+1] == '=' && strings.IndexByte("=!<>", c) >= 0 {
+				n = 2
+			}
+			value := selector[i : i+n]
+			if value == "==" {
+				value = "="
+			}
+			tokens = append(tokens, token{kind: symbol, value: value, pos: i})
+			i += n
+		default:
+			start := i
+			for i < len(selector) && !strings.ContainsRune(symbols+` "`+"\t", rune(selector[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: word, value: selector[start:i], pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// unquote reads the quoted string at the start of s, \" and \\ are escapes.
+// It returns the value and the length of the quoted string.
+func unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unterminated quoted value")
+			}
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unterminated quoted value")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parse() ([]requirement, error) {
+	var requirements []requirement
+	for p.pos < len(p.tokens) {
+		if p.accept(",") {
+			// Empty requirements, e.g. from a trailing comma, are skipped.
+			continue
+		}
+		r, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, r)
+		if p.pos < len(p.tokens) && !p.accept(",") {
+			return nil, fmt.Errorf("at %d: expected , but got %s", p.tokens[p.pos].pos, p.tokens[p.pos])
+		}
+	}
+	return requirements, nil
+}
+
+func (p *parser) requirement() (requirement, error) {
+	if p.accept("!") {
+		key, err := p.key()
+		return requirement{key: key, operator: notExists}, err
+	}
+	key, err := p.key()
+	if err != nil {
+		return requirement{}, err
+	}
+
+	next, ok := p.peek()
+	if !ok || (next.kind == symbol && next.value == ",") {
+		return requirement{key: key, operator: exists}, nil
+	}
+	p.pos++
+	switch {
+	case next.kind == word && (next.value == "in" || next.value == "notin"):
+		values, err := p.list()
+		return requirement{key: key, operator: operator(next.value), values: values}, err
+	case next.kind == symbol && (next.value == "=" || next.value == "!="):
+		if t, ok := p.peek(); !ok || (t.kind == symbol && t.value == ",") {
+			// key= compares with the empty value, as in Kubernetes.
+			return requirement{key: key, operator: operator(next.value), values: []pattern{{}}}, nil
+		}
+		value, err := p.value()
+		return requirement{key: key, operator: operator(next.value), values: []pattern{value}}, err
+	case next.kind == symbol && strings.IndexByte("<>", next.value[0]) >= 0:
+		value, ok := p.peek()
+		if !ok || value.kind == symbol {
+			return requirement{}, p.unexpected("a number")
+		}
+		p.pos++
+		number, err := strconv.ParseFloat(value.value, 64)
+		if err != nil {
+			return requirement{}, fmt.Errorf("at %d: %s is not a number", value.pos, value)
+		}
+		return requirement{key: key, operator: operator(next.value), number: number}, nil
+	default:
+		p.pos--
+		return requirement{}, p.unexpected("an operator")
+	}
+}
+
+func (p *parser) key() (pattern, error) {
+	t, ok := p.peek()
+	if !ok || t.kind != word {
+		return pattern{}, p.unexpected("a label key")
+	}
+	p.pos++
+	return newPattern(t.value, true), nil
+}
+
+func (p *parser) value() (pattern, error) {
+	t, ok := p.peek()
+	if !ok || t.kind == symbol {
+		return pattern{}, p.unexpected("a value")
+	}
+	p.pos++
+	return newPattern(t.value, t.kind == word), nil
+}
+
+// list parses (value, ...).
+func (p *parser) list() ([]pattern, error) {
+	if !p.accept("(") {
+		return nil, p.unexpected("(")
+	}
+	var values []pattern
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.accept(")") {
+			return values, nil
+		}
+		if !p.accept(",") {
+			return nil, p.unexpected(", or )")
+		}
+	}
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) accept(value string) bool {
+	if t, ok := p.peek(); ok && t.kind == symbol && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) unexpected(expected string) error {
+	t, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("expected %s at the end", expected)
+	}
+	return fmt.Errorf("at %d: expected %s but got %s", t.pos, expected, t)
+}
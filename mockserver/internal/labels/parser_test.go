@@ -0,0 +1,105 @@
+package labels
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+	}{
+		{name: "two character operators first", selector: "a>=1,b<=2,c!=d,e==f", want: []string{"a", ">=", "1", ",", "b", "<=", "2", ",", "c", "!=", "d", ",", "e", "=", "f"}},
+		{name: "not exists", selector: "!a,b", want: []string{"!", "a", ",", "b"}},
+		{name: "not exists before equals", selector: "!a=b", want: []string{"!", "a", "=", "b"}},
+		{name: "quoted comma", selector: `a="x,y"`, want: []string{"a", "=", "x,y"}},
+		{name: "quoted escapes", selector: `a="say \"hi\" \\o/"`, want: []string{"a", "=", `say "hi" \o/`}},
+		{name: "whitespace", selector: " a in\t( x , y ) ", want: []string{"a", "in", "(", "x", ",", "y", ")"}},
+		{name: "empty", selector: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.selector)
+			if err != nil {
+				t.Fatalf("tokenize() error = %v", err)
+			}
+			var got []string
+			for _, token := range tokens {
+				got = append(got, token.value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("tokenize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     []operator
+	}{
+		{name: "empty", selector: ""},
+		{name: "trailing comma", selector: "a=b,", want: []operator{equals}},
+		{name: "empty requirements", selector: ",a,,b,", want: []operator{exists, exists}},
+		{name: "not exists", selector: "!a", want: []operator{notExists}},
+		{name: "exists and not exists", selector: "a,!b", want: []operator{exists, notExists}},
+		{name: "empty value", selector: "a=,b!=", want: []operator{equals, notEquals}},
+		{name: "in is a key before an operator", selector: "in=x,notin in (in)", want: []operator{equals, in}},
+		{name: "numbers", selector: "a>1,b>=1.5,c<-1,d<=2", want: []operator{greater, greaterOrEqual, less, lessOrEqual}},
+		{name: "quoted comma in list", selector: `a in ("x,y", z),b`, want: []operator{in, exists}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var got []operator
+			for _, r := range selector.requirements {
+				got = append(got, r.operator)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Parse() operators = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		selector string
+		wantErr  string
+	}{
+		{selector: `a="x`, wantErr: "at 2: unterminated quoted value"},
+		{selector: `a="x\`, wantErr: "at 2: unterminated quoted value"},
+		{selector: "a b", wantErr: `at 2: expected an operator but got "b"`},
+		{selector: "a=b c", wantErr: `at 4: expected , but got "c"`},
+		{selector: "a=(", wantErr: `at 2: expected a value but got "("`},
+		{selector: "a in x", wantErr: `at 5: expected ( but got "x"`},
+		{selector: "a in (x", wantErr: "expected , or ) at the end"},
+		{selector: "a in (x,)", wantErr: `at 8: expected a value but got ")"`},
+		{selector: "a in ()", wantErr: `at 6: expected a value but got ")"`},
+		{selector: "a>", wantErr: "expected a number at the end"},
+		{selector: "a>b", wantErr: `at 2: "b" is not a number`},
+		{selector: "a>=,b", wantErr: `at 3: expected a number but got ","`},
+		{selector: "!", wantErr: "expected a label key at the end"},
+		{selector: "!=a", wantErr: `at 0: expected a label key but got "!="`},
+		{selector: `"a"=b`, wantErr: `at 0: expected a label key but got "a"`},
+		{selector: "a,=b", wantErr: `at 2: expected a label key but got "="`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			_, err := Parse(tt.selector)
+			if err == nil {
+				t.Fatal("Parse() succeeded")
+			}
+			if want := "invalid label selector: " + tt.wantErr; err.Error() != want {
+				t.Fatalf("Parse() error = %q, want %q", err, want)
+			}
+		})
+	}
+}
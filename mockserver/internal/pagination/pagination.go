@@ -0,0 +1,54 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var ErrInvalidSkipToken = errors.New("invalid skipToken")
+
+// Page sorts items by key and returns the page selected by limit and
+// skipToken together with the token of the next page, if there is one.
+// The token encodes the key of the last returned item, so pages stay stable
+// while items are added or removed between requests.
+func Page[T any](items []T, key func(T) string, limit *int, skipToken *string) ([]T, *string, error) {
+	size := DefaultLimit
+	if limit != nil {
+		if *limit < 1 || *limit > MaxLimit {
+			return nil, nil, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		size = *limit
+	}
+
+	sorted := make([]T, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return key(sorted[i]) < key(sorted[j])
+	})
+
+	start := 0
+	if skipToken != nil && *skipToken != "" {
+		after, err := base64.RawURLEncoding.DecodeString(*skipToken)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSkipToken, err)
+		}
+		start = sort.Search(len(sorted), func(i int) bool {
+			return key(sorted[i]) > string(after)
+		})
+	}
+
+	end := min(start+size, len(sorted))
+	page := sorted[start:end]
+	if end == len(sorted) {
+		return page, nil, nil
+	}
+	next := base64.RawURLEncoding.EncodeToString([]byte(key(page[len(page)-1])))
+	return page, &next, nil
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func identity(s string) string { return s }
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPage(t *testing.T) {
+	items := []string{"c", "a", "e", "b", "d"}
+
+	tests := []struct {
+		name      string
+		limit     *int
+		skipToken *string
+		want      []string
+		wantNext  bool
+	}{
+		{name: "default limit", want: []string{"a", "b", "c", "d", "e"}},
+		{name: "first page", limit: ptr(2), want: []string{"a", "b"}, wantNext: true},
+		{name: "empty token", limit: ptr(2), skipToken: ptr(""), want: []string{"a", "b"}, wantNext: true},
+		{name: "exact last page", limit: ptr(5), want: []string{"a", "b", "c", "d", "e"}},
+		{name: "after token", limit: ptr(2), skipToken: ptr("Yg"), want: []string{"c", "d"}, wantNext: true},
+		// A token of a removed item continues with the next key.
+		{name: "after removed item", limit: ptr(2), skipToken: ptr("YmI"), want: []string{"c", "d"}, wantNext: true},
+		{name: "past the end", limit: ptr(2), skipToken: ptr("eg"), want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := Page(items, identity, tt.limit, tt.skipToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Page() = %v, want %v", got, tt.want)
+			}
+			if (next != nil) != tt.wantNext {
+				t.Errorf("Page() next = %v, want next %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestPageWalk(t *testing.T) {
+	items := []string{"c", "a", "e", "b", "d"}
+
+	var got []string
+	var token *string
+	for pages := 0; ; pages++ {
+		if pages > len(items) {
+			t.Fatal("pagination does not terminate")
+		}
+		page, next, err := Page(items, identity, ptr(2), token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, page...)
+		if next == nil {
+			break
+		}
+		token = next
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+	if want := []string{"c", "a", "e", "b", "d"}; !reflect.DeepEqual(items, want) {
+		t.Fatalf("Page() modified its input: %v", items)
+	}
+}
+
+func TestPageInvalid(t *testing.T) {
+	items := []string{"a"}
+	for _, limit := range []int{0, -1, MaxLimit + 1} {
+		if _, _, err := Page(items, identity, &limit, nil); err == nil {
+			t.Errorf("Page(limit=%d) succeeded, want error", limit)
+		}
+	}
+	if _, _, err := Page(items, identity, nil, ptr("not base64!")); !errors.Is(err, ErrInvalidSkipToken) {
+		t.Errorf("Page(invalid token) = %v, want %v", err, ErrInvalidSkipToken)
+	}
+}
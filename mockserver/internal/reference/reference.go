@@ -0,0 +1,48 @@
+package reference
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Name returns the name of the resource in collection a reference points to,
+// e.g. seca.d2 for collection skus. References are either a URN or resource
+// path like seca.compute/v1/tenants/t1/skus/seca.d2 or an object with a
+// resource field.
+func Name(ref any, collection string) (string, error) {
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return "", err
+	}
+
+	var resource string
+	var object struct {
+		Resource string `json:"resource"`
+	}
+	switch {
+	case json.Unmarshal(data, &resource) == nil:
+	case json.Unmarshal(data, &object) == nil:
+		resource = object.Resource
+	}
+	if resource == "" {
+		return "", fmt.Errorf("reference %s does not name a resource", data)
+	}
+
+	segments := strings.Split(strings.Trim(resource, "/"), "/")
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i] == collection && segments[i+1] != "" {
+			return segments[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("reference %q does not point to %s", resource, collection)
+}
+
+// Workspace returns the workspace a reference points into, or fallback for
+// references relative to the workspace of the referencing resource.
+func Workspace(ref any, fallback string) string {
+	if workspace, err := Name(ref, "workspaces"); err == nil {
+		return workspace
+	}
+	return fallback
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Fixture lists resources per store, e.g.
+//
+//	seca.workspace:
+//	  - metadata: {tenant: t1, name: ws1}
+//	    spec: {}
+//	    status: {state: active}
+type Fixture map[string][]json.RawMessage
+
+// ParseFixture reads a fixture in YAML or JSON.
+func ParseFixture(data []byte) (Fixture, error) {
+	var doc map[string][]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse fixture: %w", err)
+	}
+
+	fixture := make(Fixture, len(doc))
+	for name, items := range doc {
+		raw := make([]json.RawMessage, 0, len(items))
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, fmt.Errorf("parse fixture %s: %w", name, err)
+			}
+			raw = append(raw, data)
+		}
+		fixture[name] = raw
+	}
+	return fixture, nil
+}
+
+func ReadFixture(path string) (Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixture(data)
+}
+
+// YAML renders the fixture as YAML with the JSON field names of the models.
+func (f Fixture) YAML() ([]byte, error) {
+	doc := make(map[string][]any, len(f))
+	for name, items := range f {
+		values := make([]any, 0, len(items))
+		for _, item := range items {
+			var value any
+			if err := json.Unmarshal(item, &value); err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		doc[name] = values
+	}
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,265 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store is implemented by every provider server that holds state.
+type Store interface {
+	// Name identifies the store in snapshots and data files, e.g. "seca.compute".
+	Name() string
+	Snapshot() (json.RawMessage, error)
+	// PrepareRestore decodes and validates data without changing the store.
+	PrepareRestore(data json.RawMessage) (Restore, error)
+	// Reset removes all resources.
+	Reset()
+	// PrepareSeed decodes and validates the resources of a fixture without
+	// changing the store. Once swapped in, they are added in the state given
+	// by the fixture, without starting any state transitions.
+	PrepareSeed(items []json.RawMessage) (Restore, error)
+	// Dump returns all resources in the format accepted by PrepareSeed.
+	Dump() ([]json.RawMessage, error)
+}
+
+// Restore changes the state of a store to a prepared snapshot or fixture.
+// Swap is called with the lock held, Done after the lock is released.
+type Restore interface {
+	Lock()
+	Unlock()
+	Swap()
+	Done()
+}
+
+// Snapshot is the full state of all stores, keyed by store name.
+type Snapshot map[string]json.RawMessage
+
+type Registry struct {
+	mu     sync.Mutex
+	stores map[string]Store
+	// written holds the last persisted data per store to skip unchanged files.
+	written map[string][]byte
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		stores:  map[string]Store{},
+		written: map[string][]byte{},
+	}
+}
+
+func (r *Registry) Register(store Store) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stores[store.Name()] = store
+}
+
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.stores))
+	for name := range r.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stores returns the registered stores ordered by name.
+func (r *Registry) Stores() []Store {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stores := make([]Store, 0, len(r.stores))
+	for _, name := range slices.Sorted(maps.Keys(r.stores)) {
+		stores = append(stores, r.stores[name])
+	}
+	return stores
+}
+
+func (r *Registry) Snapshot() (Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := Snapshot{}
+	for name, store := range r.stores {
+		data, err := store.Snapshot()
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", name, err)
+		}
+		snapshot[name] = data
+	}
+	return snapshot, nil
+}
+
+// Restore replaces the state of every store in snapshot. Stores missing from
+// the snapshot keep their state. All stores are decoded before any of them
+// changes and are swapped while all of them are locked, so an invalid
+// snapshot changes nothing and requests never see a partial restore.
+func (r *Registry) Restore(snapshot Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	restores := make([]Restore, 0, len(snapshot))
+	for _, name := range slices.Sorted(maps.Keys(snapshot)) {
+		store, ok := r.stores[name]
+		if !ok {
+			return fmt.Errorf("unknown store %q", name)
+		}
+		restore, err := store.PrepareRestore(snapshot[name])
+		if err != nil {
+			return fmt.Errorf("restore %s: %w", name, err)
+		}
+		restores = append(restores, restore)
+	}
+	Apply(restores...)
+	return nil
+}
+
+// Apply swaps in restores while all of them are locked, so requests never see
+// a partial change.
+func Apply(restores ...Restore) {
+	for _, restore := range restores {
+		restore.Lock()
+	}
+	for _, restore := range restores {
+		restore.Swap()
+	}
+	for i := len(restores) - 1; i >= 0; i-- {
+		restores[i].Unlock()
+	}
+	for _, restore := range restores {
+		restore.Done()
+	}
+}
+
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, store := range r.stores {
+		store.Reset()
+	}
+}
+
+// Seed adds the resources of fixture to their stores. Like Restore, all
+// stores are prepared before any of them changes, so an invalid fixture
+// changes nothing.
+func (r *Registry) Seed(fixture Fixture) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seeds := make([]Restore, 0, len(fixture))
+	for _, name := range slices.Sorted(maps.Keys(fixture)) {
+		store, ok := r.stores[name]
+		if !ok {
+			return fmt.Errorf("unknown store %q", name)
+		}
+		seed, err := store.PrepareSeed(fixture[name])
+		if err != nil {
+			return fmt.Errorf("seed %s: %w", name, err)
+		}
+		seeds = append(seeds, seed)
+	}
+	Apply(seeds...)
+	return nil
+}
+
+func (r *Registry) Dump() (Fixture, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fixture := Fixture{}
+	for name, store := range r.stores {
+		items, err := store.Dump()
+		if err != nil {
+			return nil, fmt.Errorf("dump %s: %w", name, err)
+		}
+		fixture[name] = items
+	}
+	return fixture, nil
+}
+
+// Load restores all stores from the files in dir. Missing files are skipped.
+func (r *Registry) Load(dir string) error {
+	snapshot := Snapshot{}
+	for _, name := range r.Names() {
+		data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		snapshot[name] = data
+
+		r.mu.Lock()
+		r.written[name] = data
+		r.mu.Unlock()
+	}
+	return r.Restore(snapshot)
+}
+
+// Save writes every store whose state changed since the last save to
+// <dir>/<name>.json.
+func (r *Registry) Save(dir string) error {
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, data := range snapshot {
+		if bytes.Equal(r.written[name], data) {
+			continue
+		}
+		if err := writeFileAtomic(filepath.Join(dir, name+".json"), data); err != nil {
+			return err
+		}
+		r.written[name] = data
+	}
+	return nil
+}
+
+// Persist saves the state to dir every interval until ctx is done, then
+// saves one last time.
+func (r *Registry) Persist(ctx context.Context, dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Save(dir); err != nil {
+				log.Printf("saving state to %s failed: %v", dir, err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.Save(dir); err != nil {
+				log.Printf("saving state to %s failed: %v", dir, err)
+			}
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
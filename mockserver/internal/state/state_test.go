@@ -0,0 +1,113 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fakeStore struct {
+	name string
+	data json.RawMessage
+}
+
+func (f *fakeStore) Name() string                       { return f.name }
+func (f *fakeStore) Snapshot() (json.RawMessage, error) { return f.data, nil }
+func (f *fakeStore) Reset()                             { f.data = nil }
+func (f *fakeStore) Dump() ([]json.RawMessage, error)   { return nil, nil }
+func (f *fakeStore) PrepareRestore(data json.RawMessage) (Restore, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("invalid snapshot")
+	}
+	return &fakeRestore{store: f, data: data}, nil
+}
+
+func (f *fakeStore) PrepareSeed(items []json.RawMessage) (Restore, error) {
+	for _, item := range items {
+		if !json.Valid(item) {
+			return nil, errors.New("invalid item")
+		}
+	}
+	data, _ := json.Marshal(items)
+	return &fakeRestore{store: f, data: data}, nil
+}
+
+type fakeRestore struct {
+	store  *fakeStore
+	data   json.RawMessage
+	locked bool
+}
+
+func (r *fakeRestore) Lock()   { r.locked = true }
+func (r *fakeRestore) Unlock() { r.locked = false }
+func (r *fakeRestore) Done()   {}
+func (r *fakeRestore) Swap() {
+	if !r.locked {
+		panic("swap without lock")
+	}
+	r.store.data = r.data
+}
+
+func TestRegistryRestore(t *testing.T) {
+	a := &fakeStore{name: "a", data: json.RawMessage(`1`)}
+	b := &fakeStore{name: "b", data: json.RawMessage(`2`)}
+	registry := NewRegistry()
+	registry.Register(a)
+	registry.Register(b)
+
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		wantErr  bool
+		wantA    string
+		wantB    string
+	}{
+		{name: "invalid store leaves all unchanged", snapshot: Snapshot{"a": json.RawMessage(`10`), "b": json.RawMessage(`{`)}, wantErr: true, wantA: "1", wantB: "2"},
+		{name: "unknown store leaves all unchanged", snapshot: Snapshot{"a": json.RawMessage(`10`), "c": json.RawMessage(`3`)}, wantErr: true, wantA: "1", wantB: "2"},
+		{name: "missing store keeps its state", snapshot: Snapshot{"a": json.RawMessage(`10`)}, wantA: "10", wantB: "2"},
+		{name: "all stores", snapshot: Snapshot{"a": json.RawMessage(`11`), "b": json.RawMessage(`12`)}, wantA: "11", wantB: "12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Restore(tt.snapshot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Restore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(a.data) != tt.wantA || string(b.data) != tt.wantB {
+				t.Fatalf("state = %s, %s, want %s, %s", a.data, b.data, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestRegistrySeed(t *testing.T) {
+	a := &fakeStore{name: "a", data: json.RawMessage(`[]`)}
+	b := &fakeStore{name: "b", data: json.RawMessage(`[]`)}
+	registry := NewRegistry()
+	registry.Register(a)
+	registry.Register(b)
+
+	tests := []struct {
+		name    string
+		fixture Fixture
+		wantErr bool
+		wantA   string
+		wantB   string
+	}{
+		{name: "invalid item leaves all unchanged", fixture: Fixture{"a": {json.RawMessage(`1`)}, "b": {json.RawMessage(`{`)}}, wantErr: true, wantA: "[]", wantB: "[]"},
+		{name: "unknown store leaves all unchanged", fixture: Fixture{"a": {json.RawMessage(`1`)}, "c": {json.RawMessage(`3`)}}, wantErr: true, wantA: "[]", wantB: "[]"},
+		{name: "missing store keeps its state", fixture: Fixture{"a": {json.RawMessage(`1`)}}, wantA: "[1]", wantB: "[]"},
+		{name: "all stores", fixture: Fixture{"a": {json.RawMessage(`2`)}, "b": {json.RawMessage(`3`)}}, wantA: "[2]", wantB: "[3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Seed(tt.fixture)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Seed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(a.data) != tt.wantA || string(b.data) != tt.wantB {
+				t.Fatalf("state = %s, %s, want %s, %s", a.data, b.data, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"encoding/json"
+	"maps"
+	"time"
+
+	"cape-project.eu/mockserver/models"
+)
+
+// The store handles all resource types through their JSON documents, which
+// share the metadata and the state part of the status.
+
+// Metadata holds the fields of the regional (workspace) resource metadata.
+type Metadata struct {
+	ApiVersion      string     `json:"apiVersion"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	DeletedAt       *time.Time `json:"deletedAt,omitempty"`
+	Kind            string     `json:"kind"`
+	LastModifiedAt  time.Time  `json:"lastModifiedAt"`
+	Name            string     `json:"name"`
+	Provider        string     `json:"provider"`
+	Region          string     `json:"region"`
+	Resource        string     `json:"resource"`
+	ResourceVersion int64      `json:"resourceVersion"`
+	Tenant          string     `json:"tenant"`
+	Verb            string     `json:"verb"`
+	Workspace       string     `json:"workspace,omitempty"`
+}
+
+// Status holds the fields every resource status has.
+type Status struct {
+	State      *models.ResourceState    `json:"state,omitempty"`
+	Conditions []models.StatusCondition `json:"conditions"`
+}
+
+type envelope struct {
+	Labels   models.Labels `json:"labels"`
+	Metadata *Metadata     `json:"metadata"`
+	Status   *Status       `json:"status"`
+}
+
+func inspect[T any](item T) envelope {
+	var e envelope
+	if data, err := json.Marshal(item); err == nil {
+		_ = json.Unmarshal(data, &e)
+	}
+	return e
+}
+
+func metadataOf[T any](item T) Metadata {
+	if metadata := inspect(item).Metadata; metadata != nil {
+		return *metadata
+	}
+	return Metadata{}
+}
+
+func scopeOf[T any](item T) (Scope, bool) {
+	metadata := inspect(item).Metadata
+	if metadata == nil || metadata.Tenant == "" || metadata.Name == "" {
+		return Scope{}, false
+	}
+	return Scope{Tenant: metadata.Tenant, Workspace: metadata.Workspace, Name: metadata.Name}, true
+}
+
+// patch applies fn to the JSON document of item. Fields of the type that fn
+// does not touch are kept.
+func patch[T any](item T, fn func(doc map[string]any) error) (T, error) {
+	var zero T
+	data, err := json.Marshal(item)
+	if err != nil {
+		return zero, err
+	}
+	doc := map[string]any{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return zero, err
+	}
+	if err := fn(doc); err != nil {
+		return zero, err
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return zero, err
+	}
+	var patched T
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return zero, err
+	}
+	return patched, nil
+}
+
+// patchMetadata replaces the common metadata fields of item.
+func patchMetadata[T any](item T, metadata Metadata) (T, error) {
+	return patch(item, func(doc map[string]any) error {
+		return merge(doc, "metadata", metadata)
+	})
+}
+
+// patchStatus lets fn change the state and conditions of item, creating the
+// status if there is none.
+func patchStatus[T any](item T, fn func(status *Status)) (T, error) {
+	return patch(item, func(doc map[string]any) error {
+		var status Status
+		if current, ok := doc["status"]; ok && current != nil {
+			data, err := json.Marshal(current)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &status); err != nil {
+				return err
+			}
+		}
+		if status.Conditions == nil {
+			status.Conditions = []models.StatusCondition{}
+		}
+		fn(&status)
+		return merge(doc, "status", status)
+	})
+}
+
+// merge sets the fields of value in the object doc[field].
+func merge(doc map[string]any, field string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	object, ok := doc[field].(map[string]any)
+	if !ok {
+		object = map[string]any{}
+	}
+	maps.Copy(object, fields)
+	doc[field] = object
+	return nil
+}
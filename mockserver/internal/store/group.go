@@ -0,0 +1,169 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/state"
+)
+
+// Group combines the stores of a provider into one state.Store. Snapshots
+// hold the resources per collection, fixtures tell them apart by
+// metadata.kind, items without a kind belong to the first collection.
+type Group struct {
+	name        string
+	collections []Collection
+}
+
+func NewGroup(name string, collections ...Collection) *Group {
+	return &Group{name: name, collections: collections}
+}
+
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) Collections() []Collection {
+	return g.collections
+}
+
+func (g *Group) Snapshot() (json.RawMessage, error) {
+	snapshot := make(map[string]json.RawMessage, len(g.collections))
+	for _, collection := range g.collections {
+		data, err := collection.Snapshot()
+		if err != nil {
+			return nil, err
+		}
+		snapshot[snapshotKey(collection.Kind())] = data
+	}
+	return json.Marshal(snapshot)
+}
+
+// PrepareRestore prepares replacing the resources of all collections,
+// collections missing in data are emptied.
+func (g *Group) PrepareRestore(data json.RawMessage) (state.Restore, error) {
+	var snapshot map[string]json.RawMessage
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	restores := make(groupRestore, 0, len(g.collections))
+	for _, collection := range g.collections {
+		data, ok := snapshot[snapshotKey(collection.Kind())]
+		if !ok {
+			data = json.RawMessage("{}")
+		}
+		restore, err := collection.PrepareRestore(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", collection.Kind().Collection, err)
+		}
+		restores = append(restores, restore)
+	}
+	return restores, nil
+}
+
+type groupRestore []state.Restore
+
+func (r groupRestore) Lock() {
+	for _, restore := range r {
+		restore.Lock()
+	}
+}
+
+func (r groupRestore) Unlock() {
+	for i := len(r) - 1; i >= 0; i-- {
+		r[i].Unlock()
+	}
+}
+
+func (r groupRestore) Swap() {
+	for _, restore := range r {
+		restore.Swap()
+	}
+}
+
+func (r groupRestore) Done() {
+	for _, restore := range r {
+		restore.Done()
+	}
+}
+
+func (g *Group) Reset() {
+	for _, collection := range g.collections {
+		collection.Reset()
+	}
+}
+
+// PrepareSeed prepares adding the resources of a fixture to their
+// collections.
+func (g *Group) PrepareSeed(items []json.RawMessage) (state.Restore, error) {
+	if len(g.collections) == 0 {
+		return nil, fmt.Errorf("%s holds no resources", g.name)
+	}
+
+	byKind := make(map[string][]json.RawMessage, len(g.collections))
+	for i, item := range items {
+		var kind struct {
+			Metadata struct {
+				Kind string `json:"kind"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(item, &kind); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		name := kind.Metadata.Kind
+		if name == "" {
+			name = g.collections[0].Kind().Name
+		}
+		if g.collection(name) == nil {
+			return nil, fmt.Errorf("item %d: unknown kind %q", i, name)
+		}
+		byKind[name] = append(byKind[name], item)
+	}
+
+	seeds := make(groupRestore, 0, len(g.collections))
+	for _, collection := range g.collections {
+		seed, err := collection.PrepareSeed(byKind[collection.Kind().Name])
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// Dump returns the resources of all collections in order.
+func (g *Group) Dump() ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	for _, collection := range g.collections {
+		dumped, err := collection.Dump()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, dumped...)
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	return items, nil
+}
+
+func (g *Group) collection(kind string) Collection {
+	for _, collection := range g.collections {
+		if collection.Kind().Name == kind {
+			return collection
+		}
+	}
+	return nil
+}
+
+// snapshotKey returns the collection in lower camel case, e.g. blockStorages.
+func snapshotKey(kind Kind) string {
+	parts := strings.Split(kind.Collection, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
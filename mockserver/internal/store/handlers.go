@@ -0,0 +1,295 @@
+package store
+
+import (
+	"fmt"
+	"net/http"
+
+	"cape-project.eu/mockserver/internal/conditional"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/labels"
+	"cape-project.eu/mockserver/internal/pagination"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Iterator is the response of a list operation, it has the JSON format of the
+// generated <Kind>Iterator types.
+type Iterator[T any] struct {
+	Items    []T                     `json:"items"`
+	Metadata models.ResponseMetadata `json:"metadata"`
+}
+
+// List responds with a page of the resources in the tenant or workspace of
+// scope that match the label selector, or watches them with ?watch=true.
+func (s *Store[T]) List(c *gin.Context, scope Scope, selector *string, limit *int, skipToken *string) {
+	var filter labels.Selector
+	if selector != nil {
+		var err error
+		if filter, err = labels.Parse(*selector); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if c.Query("watch") == "true" {
+		s.Watch(c, scope, filter)
+		return
+	}
+
+	items := make([]T, 0)
+	if s.Static != nil {
+		for _, item := range s.Static(scope.Tenant) {
+			if metadataOf(item).Workspace == scope.Workspace {
+				items = append(items, item)
+			}
+		}
+	}
+
+	s.mu.RLock()
+	for _, item := range s.items {
+		metadata := metadataOf(item)
+		if metadata.Tenant == scope.Tenant && metadata.Workspace == scope.Workspace {
+			items = append(items, item)
+		}
+	}
+	s.mu.RUnlock()
+
+	filtered := items[:0]
+	for _, item := range items {
+		if filter.Matches(inspect(item).Labels) {
+			filtered = append(filtered, item)
+		}
+	}
+	items = filtered
+
+	page, next, err := pagination.Page(items, func(item T) string {
+		return metadataOf(item).Name
+	}, limit, skipToken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, Iterator[T]{
+		Items: page,
+		Metadata: models.ResponseMetadata{
+			Provider:  s.kind.Provider + "/" + s.kind.APIVersion,
+			Resource:  s.kind.resource(Scope{Tenant: scope.Tenant, Workspace: scope.Workspace}),
+			Verb:      "list",
+			SkipToken: next,
+		},
+	})
+}
+
+func (s *Store[T]) Get(c *gin.Context, scope Scope) {
+	if item, ok := s.static(scope); ok {
+		c.JSON(http.StatusOK, item)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[scope.key()]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s not found", s.kind.Name)})
+		return
+	}
+
+	conditional.SetETag(c, metadataOf(item).ResourceVersion)
+	c.JSON(http.StatusOK, item)
+}
+
+// Put creates or updates the resource in scope. The status is owned by the
+// store, only the fields set by the Validate hook are taken from the request.
+// Resources that are being deleted can no longer be written (409).
+func (s *Store[T]) Put(c *gin.Context, scope Scope) {
+	var item T
+	if err := c.ShouldBindJSON(&item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := s.static(scope); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s %s is read-only", s.kind.Name, scope.Name)})
+		return
+	}
+	item, err := patch(item, func(doc map[string]any) error {
+		delete(doc, "metadata")
+		delete(doc, "status")
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The referenced resources must not be deleted before item is stored.
+	s.integrity.Lock()
+	defer s.integrity.Unlock()
+	if s.Validate != nil {
+		if status, err := s.Validate(scope, &item); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if status, err := s.checkReferences(scope, item); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope.key()
+	existing, exists := s.items[key]
+	var metadata Metadata
+	if exists {
+		metadata = metadataOf(existing)
+	}
+	if !conditional.Check(c, exists, metadata.ResourceVersion) {
+		return
+	}
+	if exists && deleting(existing) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s %s is being deleted", s.kind.Name, scope.Name)})
+		return
+	}
+
+	if !exists {
+		metadata.CreatedAt = now
+	}
+	metadata.ApiVersion = s.kind.APIVersion
+	metadata.Kind = s.kind.Name
+	metadata.LastModifiedAt = now
+	metadata.Name = scope.Name
+	metadata.Provider = s.kind.Provider
+	metadata.Region = Region
+	metadata.Resource = s.kind.resource(scope)
+	metadata.ResourceVersion++
+	metadata.Tenant = scope.Tenant
+	metadata.Verb = "put"
+	metadata.Workspace = scope.Workspace
+
+	state := models.ResourceStatePending
+	var existingItem *T
+	if exists {
+		state = models.ResourceStateUpdating
+		existingItem = &existing
+	}
+	item, err = s.write(item, existingItem, metadata)
+	if err == nil {
+		err = s.setState(&item, state, now)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.items[key] = item
+	if exists {
+		s.publish(Updated, item)
+	} else {
+		s.publish(Created, item)
+	}
+	version := metadata.ResourceVersion
+	conditional.SetETag(c, version)
+	if !exists {
+		s.scheduleStateTransition(scope, version, s.delays.Creating, models.ResourceStateCreating)
+		s.scheduleStateTransition(scope, version, s.delays.Active, models.ResourceStateActive)
+		c.JSON(http.StatusCreated, s.items[key])
+		return
+	}
+	s.scheduleStateTransition(scope, version, s.delays.Update, models.ResourceStateActive)
+	c.JSON(http.StatusOK, s.items[key])
+}
+
+// write sets the metadata of item and keeps the state and conditions of the
+// existing resource.
+func (s *Store[T]) write(item T, existing *T, metadata Metadata) (T, error) {
+	item, err := patchMetadata(item, metadata)
+	if err != nil {
+		return item, err
+	}
+	item, err = patchStatus(item, func(status *Status) {
+		if existing == nil {
+			return
+		}
+		if current := inspect(*existing).Status; current != nil {
+			status.State = current.State
+			if current.Conditions != nil {
+				status.Conditions = current.Conditions
+			}
+		}
+	})
+	if err != nil {
+		return item, err
+	}
+	if s.OnWrite != nil {
+		s.OnWrite(&item, existing)
+	}
+	return item, nil
+}
+
+func (s *Store[T]) Delete(c *gin.Context, scope Scope) {
+	if _, ok := s.static(scope); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s %s is read-only", s.kind.Name, scope.Name)})
+		return
+	}
+
+	// No resource may start referencing it before it is deleting.
+	s.integrity.Lock()
+	defer s.integrity.Unlock()
+	if err := s.integrity.CheckUnreferenced(s.kind.ref(scope)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope.key()
+	item, ok := s.existing(c, key)
+	if !ok {
+		return
+	}
+	version := metadataOf(item).ResourceVersion
+
+	if err := s.setState(&item, models.ResourceStateDeleting, s.clock.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.items[key] = item
+	s.publish(StateChanged, item)
+	s.scheduleDeletion(scope, version, s.delays.Delete)
+
+	response := gin.H{
+		"deleted": true,
+		"tenant":  scope.Tenant,
+		"name":    scope.Name,
+	}
+	if s.kind.Workspaced {
+		response["workspace"] = scope.Workspace
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// checkReferences checks that the workspace and the resources item references
+// exist. It returns the HTTP status to respond with on failure.
+func (s *Store[T]) checkReferences(scope Scope, item T) (int, error) {
+	if s.kind.Workspaced && !s.integrity.Exists(integrity.Ref{Kind: "workspace", Tenant: scope.Tenant, Name: scope.Workspace}) {
+		return http.StatusNotFound, fmt.Errorf("workspace %s not found", scope.Workspace)
+	}
+	if s.References == nil {
+		return 0, nil
+	}
+	for _, ref := range s.References(scope, item) {
+		if ref.Err != nil {
+			return http.StatusUnprocessableEntity, fmt.Errorf("%s: %w", ref.Path, ref.Err)
+		}
+		if !s.integrity.Exists(ref.Ref) {
+			return http.StatusUnprocessableEntity, fmt.Errorf("%s: %s not found", ref.Path, ref.Ref)
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/models"
+)
+
+func (s *Store[T]) Exists(ref integrity.Ref) bool {
+	item, ok := s.Find(Scope{Tenant: ref.Tenant, Workspace: ref.Workspace, Name: ref.Name})
+	return ok && !deleting(item)
+}
+
+func (s *Store[T]) Resource(ref integrity.Ref) (any, bool) {
+	return s.Find(Scope{Tenant: ref.Tenant, Workspace: ref.Workspace, Name: ref.Name})
+}
+
+// ReferencesTo returns the resources in a workspace or referencing ref
+// through the References hook.
+func (s *Store[T]) ReferencesTo(ref integrity.Ref) []integrity.Ref {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var refs []integrity.Ref
+	for _, item := range s.sortedItems() {
+		scope, ok := scopeOf(item)
+		if !ok || scope.Tenant != ref.Tenant || deleting(item) {
+			continue
+		}
+		if s.references(scope, item, ref) {
+			refs = append(refs, s.kind.ref(scope))
+		}
+	}
+	return refs
+}
+
+func (s *Store[T]) references(scope Scope, item T, ref integrity.Ref) bool {
+	if ref.Kind == "workspace" && s.kind.Workspaced && scope.Workspace == ref.Name {
+		return true
+	}
+	if s.References == nil {
+		return false
+	}
+	for _, reference := range s.References(scope, item) {
+		if reference.Err == nil && reference.Ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func deleting[T any](item T) bool {
+	status := inspect(item).Status
+	return status != nil && status.State != nil && *status.State == models.ResourceStateDeleting
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"cape-project.eu/mockserver/internal/state"
+	"cape-project.eu/mockserver/models"
+)
+
+// Collection is the part of a Store that is independent of the resource type.
+type Collection interface {
+	Kind() Kind
+	Snapshot() (json.RawMessage, error)
+	PrepareRestore(data json.RawMessage) (state.Restore, error)
+	Reset()
+	PrepareSeed(items []json.RawMessage) (state.Restore, error)
+	Dump() ([]json.RawMessage, error)
+}
+
+func (s *Store[T]) Snapshot() (json.RawMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(s.items)
+}
+
+// PrepareRestore decodes a snapshot of all resources. Once swapped in,
+// resources in a transitional state continue their state transition.
+func (s *Store[T]) PrepareRestore(data json.RawMessage) (state.Restore, error) {
+	var restored map[string]T
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return nil, err
+	}
+	restore := &storeRestore[T]{store: s, items: make(map[string]T, len(restored))}
+	for _, key := range slices.Sorted(maps.Keys(restored)) {
+		item := restored[key]
+		scope, ok := scopeOf(item)
+		if !ok {
+			return nil, fmt.Errorf("%s %s: metadata.tenant and metadata.name are required", s.kind.Name, key)
+		}
+		if s.kind.Workspaced && scope.Workspace == "" {
+			return nil, fmt.Errorf("%s %s: metadata.workspace is required", s.kind.Name, key)
+		}
+		restore.items[scope.key()] = item
+		restore.scopes = append(restore.scopes, scope)
+	}
+	return restore, nil
+}
+
+type storeRestore[T any] struct {
+	store  *Store[T]
+	items  map[string]T
+	scopes []Scope
+}
+
+func (r *storeRestore[T]) Lock() {
+	r.store.mu.Lock()
+}
+
+func (r *storeRestore[T]) Unlock() {
+	r.store.mu.Unlock()
+}
+
+func (r *storeRestore[T]) Swap() {
+	s := r.store
+	s.epoch++
+	s.closeWatches()
+	s.items = r.items
+	for _, scope := range r.scopes {
+		item := s.items[scope.key()]
+		status := inspect(item).Status
+		if status == nil || status.State == nil {
+			continue
+		}
+		version := metadataOf(item).ResourceVersion
+		switch *status.State {
+		case models.ResourceStatePending, models.ResourceStateCreating, models.ResourceStateUpdating:
+			s.scheduleStateTransition(scope, version, s.delays.Update, models.ResourceStateActive)
+		case models.ResourceStateDeleting:
+			s.scheduleDeletion(scope, version, s.delays.Delete)
+		}
+	}
+}
+
+func (r *storeRestore[T]) Done() {
+	s := r.store
+	if s.OnRestore == nil {
+		return
+	}
+	for _, scope := range r.scopes {
+		if item, ok := s.Find(scope); ok {
+			s.OnRestore(scope, item)
+		}
+	}
+}
+
+func (s *Store[T]) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epoch++
+	s.closeWatches()
+	s.items = map[string]T{}
+}
+
+// Seed adds resources like a fixture, see PrepareSeed.
+func (s *Store[T]) Seed(items []json.RawMessage) error {
+	seed, err := s.PrepareSeed(items)
+	if err != nil {
+		return err
+	}
+	state.Apply(seed)
+	return nil
+}
+
+// PrepareSeed decodes resources as they are given. Metadata that is derived
+// from the path is filled in, a missing state defaults to active.
+func (s *Store[T]) PrepareSeed(items []json.RawMessage) (state.Restore, error) {
+	now := s.clock.Now()
+	seed := &storeSeed[T]{store: s, items: make(map[string]T, len(items)), states: map[string]models.ResourceState{}}
+	for i, data := range items {
+		var item T
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("%s %d: %w", s.kind.Name, i, err)
+		}
+		metadata := metadataOf(item)
+		if metadata.Tenant == "" || metadata.Name == "" || (s.kind.Workspaced && metadata.Workspace == "") {
+			if s.kind.Workspaced {
+				return nil, fmt.Errorf("%s %d: metadata.tenant, metadata.workspace and metadata.name are required", s.kind.Name, i)
+			}
+			return nil, fmt.Errorf("%s %d: metadata.tenant and metadata.name are required", s.kind.Name, i)
+		}
+
+		scope := Scope{Tenant: metadata.Tenant, Workspace: metadata.Workspace, Name: metadata.Name}
+		metadata.ApiVersion = s.kind.APIVersion
+		metadata.Kind = s.kind.Name
+		metadata.Provider = s.kind.Provider
+		metadata.Resource = s.kind.resource(scope)
+		metadata.Verb = "put"
+		if metadata.Region == "" {
+			metadata.Region = Region
+		}
+		if metadata.CreatedAt.IsZero() {
+			metadata.CreatedAt = now
+		}
+		if metadata.LastModifiedAt.IsZero() {
+			metadata.LastModifiedAt = metadata.CreatedAt
+		}
+		if metadata.ResourceVersion == 0 {
+			metadata.ResourceVersion = 1
+		}
+
+		state := models.ResourceStateActive
+		item, err := patchMetadata(item, metadata)
+		if err == nil {
+			item, err = patch(item, func(doc map[string]any) error {
+				if status, ok := doc["status"].(map[string]any); ok {
+					if given, ok := status["state"].(string); ok {
+						state = models.ResourceState(given)
+					}
+					delete(status, "state")
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			_, _, err = s.changeState(&item, state, now)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", s.kind.Name, i, err)
+		}
+		seed.items[scope.key()] = item
+		seed.states[scope.key()] = state
+	}
+	return seed, nil
+}
+
+type storeSeed[T any] struct {
+	store  *Store[T]
+	items  map[string]T
+	states map[string]models.ResourceState
+}
+
+func (r *storeSeed[T]) Lock() {
+	r.store.mu.Lock()
+}
+
+func (r *storeSeed[T]) Unlock() {
+	r.store.mu.Unlock()
+}
+
+// Swap adds the seeded resources and calls OnTransition for them.
+func (r *storeSeed[T]) Swap() {
+	s := r.store
+	for _, key := range slices.Sorted(maps.Keys(r.items)) {
+		item := r.items[key]
+		if s.OnTransition != nil {
+			s.OnTransition(&item, nil, r.states[key])
+		}
+		if _, exists := s.items[key]; exists {
+			s.publish(Updated, item)
+		} else {
+			s.publish(Created, item)
+		}
+		s.items[key] = item
+	}
+}
+
+func (r *storeSeed[T]) Done() {}
+
+func (s *Store[T]) Dump() ([]json.RawMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]json.RawMessage, 0, len(s.items))
+	for _, item := range s.sortedItems() {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
@@ -0,0 +1,382 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/internal/conditional"
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/faults"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/timing"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Region is the region of all resources and SKUs of the mockserver.
+const Region = "global"
+
+// Kind describes a resource collection of a provider.
+type Kind struct {
+	// Name is the metadata kind, e.g. block-storage.
+	Name string
+	// Title names the kind in status messages, e.g. BlockStorage.
+	Title      string
+	Provider   string
+	APIVersion string
+	// Collection is the path segment of the collection, e.g. block-storages.
+	Collection string
+	// Workspaced resources live in a workspace.
+	Workspaced bool
+}
+
+// Scope identifies a resource, Workspace is empty for tenant level resources.
+type Scope struct {
+	Tenant    string
+	Workspace string
+	Name      string
+}
+
+func (s Scope) key() string {
+	return fmt.Sprintf("%s/%s/%s", s.Tenant, s.Workspace, s.Name)
+}
+
+func (k Kind) ref(scope Scope) integrity.Ref {
+	return integrity.Ref{Kind: k.Name, Tenant: scope.Tenant, Workspace: scope.Workspace, Name: scope.Name}
+}
+
+// resource returns the path of the resource in scope, or of its collection if
+// scope has no name.
+func (k Kind) resource(scope Scope) string {
+	path := fmt.Sprintf("tenants/%s/%s", scope.Tenant, k.Collection)
+	if k.Workspaced {
+		path = fmt.Sprintf("tenants/%s/workspaces/%s/%s", scope.Tenant, scope.Workspace, k.Collection)
+	}
+	if scope.Name == "" {
+		return path
+	}
+	return path + "/" + scope.Name
+}
+
+// Reference is a reference of a resource to another one. Path names the
+// referencing field in error messages, Err is set if the field cannot be
+// resolved.
+type Reference struct {
+	Path string
+	Ref  integrity.Ref
+	Err  error
+}
+
+// Store keeps the resources of one kind and implements the CRUD operations
+// of the SecAPI on them, including the state transitions. The hooks let
+// servers add the rules of a kind, they must be set before the store is used.
+type Store[T any] struct {
+	kind  Kind
+	mu    sync.RWMutex
+	items map[string]T
+	// epoch changes whenever the state is replaced, pending transitions of
+	// an older epoch are dropped.
+	epoch     uint64
+	clock     clock.Clock
+	delays    timing.Delays
+	faults    *faults.Injector
+	integrity *integrity.Registry
+	watchers  map[*watcher]struct{}
+
+	// Static returns read-only resources of a tenant that always exist,
+	// e.g. public images, in any of its workspaces.
+	Static func(tenant string) []T
+	// Validate checks a resource before it is written, with the integrity
+	// lock but not the store lock held. It may set status fields that are
+	// derived from other resources.
+	// The returned status is the HTTP status to respond with on failure.
+	Validate func(scope Scope, item *T) (int, error)
+	// References returns the references of a resource to other resources.
+	// They must exist and not be deleting when the resource is written, and
+	// keep the referenced resources from being deleted.
+	References func(scope Scope, item T) []Reference
+	// OnWrite is called with the lock held before a resource is stored by a
+	// PUT, existing is nil when it is created.
+	OnWrite func(item, existing *T)
+	// OnTransition is called with the lock held after the state of a
+	// resource changed. from is nil for seeded resources.
+	OnTransition func(item *T, from *models.ResourceState, to models.ResourceState)
+	// OnRestore is called for every restored resource after a restore.
+	OnRestore func(scope Scope, item T)
+}
+
+// New creates a store for kind and registers it as owner of the kind.
+func New[T any](kind Kind, e env.Env) *Store[T] {
+	if kind.APIVersion == "" {
+		kind.APIVersion = "v1"
+	}
+	s := &Store[T]{
+		kind:      kind,
+		items:     map[string]T{},
+		clock:     e.Clock,
+		delays:    e.Timings.For(kind.Name),
+		faults:    e.Faults,
+		integrity: e.Integrity,
+		watchers:  map[*watcher]struct{}{},
+	}
+	e.Integrity.Register(s, kind.Name)
+	return s
+}
+
+func (s *Store[T]) Kind() Kind {
+	return s.kind
+}
+
+// Delays returns the state transition delays of the kind.
+func (s *Store[T]) Delays() timing.Delays {
+	return s.delays
+}
+
+// Find returns the resource in scope, including static resources.
+func (s *Store[T]) Find(scope Scope) (T, bool) {
+	if item, ok := s.static(scope); ok {
+		return item, true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[scope.key()]
+	return item, ok
+}
+
+// Items returns the stored resources ordered by tenant, workspace and name,
+// without static resources.
+func (s *Store[T]) Items() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedItems()
+}
+
+// Remove deletes the resource in scope right away, without a deleting state
+// or reference checks.
+func (s *Store[T]) Remove(scope Scope) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := scope.key()
+	item, ok := s.items[key]
+	if !ok {
+		return false
+	}
+	delete(s.items, key)
+	s.publish(Deleted, item)
+	return true
+}
+
+// Modify changes the resource in scope with fn after checking the
+// preconditions of the request and counts it as a write to the resource. It
+// responds with 404, 412, 409 if the resource is being deleted or the status
+// returned by fn on failure, the caller responds on success.
+func (s *Store[T]) Modify(c *gin.Context, scope Scope, fn func(item *T) (int, error)) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero T
+	key := scope.key()
+	item, ok := s.existing(c, key)
+	if !ok {
+		return zero, false
+	}
+	if deleting(item) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s %s is being deleted", s.kind.Name, scope.Name)})
+		return zero, false
+	}
+	if status, err := fn(&item); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return zero, false
+	}
+	metadata := metadataOf(item)
+	metadata.LastModifiedAt = s.clock.Now()
+	metadata.ResourceVersion++
+	item, err := patchMetadata(item, metadata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return zero, false
+	}
+	conditional.SetETag(c, metadata.ResourceVersion)
+	s.items[key] = item
+	s.publish(Updated, item)
+	return item, true
+}
+
+// existing returns the stored resource for a write to it after checking the
+// preconditions of the request. It responds with 404 or 412 on failure. The
+// caller must hold the lock.
+func (s *Store[T]) existing(c *gin.Context, key string) (T, bool) {
+	item, ok := s.items[key]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s not found", s.kind.Name)})
+		return item, false
+	}
+	if !conditional.Check(c, true, metadataOf(item).ResourceVersion) {
+		return item, false
+	}
+	return item, true
+}
+
+// After changes the resource in scope with fn after delay. The change is
+// dropped if the resource is gone or fn returns false.
+func (s *Store[T]) After(delay time.Duration, scope Scope, fn func(item *T) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scope.key()
+	s.schedule(delay, func() {
+		item, ok := s.items[key]
+		if !ok {
+			return
+		}
+		if fn(&item) {
+			s.items[key] = item
+			s.publish(Updated, item)
+		}
+	})
+}
+
+// AddCondition records a status condition in the current state of item.
+func (s *Store[T]) AddCondition(item *T, reason, message string) {
+	updated, err := patchStatus(*item, func(status *Status) {
+		state := models.ResourceState("")
+		if status.State != nil {
+			state = *status.State
+		}
+		status.Conditions = append(status.Conditions, models.StatusCondition{
+			LastTransitionAt: s.clock.Now(),
+			Message:          &message,
+			Reason:           &reason,
+			State:            state,
+		})
+	})
+	if err != nil {
+		log.Printf("%s: adding condition failed: %v", s.kind.Name, err)
+		return
+	}
+	*item = updated
+}
+
+func (s *Store[T]) static(scope Scope) (T, bool) {
+	var zero T
+	if s.Static == nil {
+		return zero, false
+	}
+	for _, item := range s.Static(scope.Tenant) {
+		if metadata := metadataOf(item); metadata.Workspace == scope.Workspace && metadata.Name == scope.Name {
+			return item, true
+		}
+	}
+	return zero, false
+}
+
+func (s *Store[T]) scheduleStateTransition(scope Scope, version int64, delay time.Duration, state models.ResourceState) {
+	s.scheduleVersion(scope, version, delay, func(key string, item T) {
+		state, ok := s.faults.Transition(s.kind.Name, scope.Name, state)
+		if !ok {
+			return
+		}
+		if err := s.setState(&item, state, s.clock.Now()); err != nil {
+			log.Printf("%s %s: state transition failed: %v", s.kind.Name, scope.Name, err)
+			return
+		}
+		s.items[key] = item
+		s.publish(StateChanged, item)
+	})
+}
+
+func (s *Store[T]) scheduleDeletion(scope Scope, version int64, delay time.Duration) {
+	s.scheduleVersion(scope, version, delay, func(key string, item T) {
+		delete(s.items, key)
+		s.publish(Deleted, item)
+	})
+}
+
+// scheduleVersion runs transition on the resource in scope after delay,
+// unless it is gone or was written again in the meantime.
+func (s *Store[T]) scheduleVersion(scope Scope, version int64, delay time.Duration, transition func(key string, item T)) {
+	key := scope.key()
+	s.schedule(delay, func() {
+		item, ok := s.items[key]
+		if !ok || metadataOf(item).ResourceVersion != version {
+			return
+		}
+		transition(key, item)
+	})
+}
+
+// schedule runs transition after delay on the store clock, or right away
+// with the lock held by the caller if there is no delay.
+func (s *Store[T]) schedule(delay time.Duration, transition func()) {
+	if delay <= 0 {
+		transition()
+		return
+	}
+	epoch := s.epoch
+	s.clock.AfterFunc(delay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.epoch != epoch {
+			return
+		}
+		transition()
+	})
+}
+
+// setState moves item to state, records a status condition and calls
+// OnTransition. The caller must hold the lock.
+func (s *Store[T]) setState(item *T, state models.ResourceState, now time.Time) error {
+	from, changed, err := s.changeState(item, state, now)
+	if err != nil {
+		return err
+	}
+	if changed && s.OnTransition != nil {
+		s.OnTransition(item, from, state)
+	}
+	return nil
+}
+
+// changeState moves item to state and records a status condition. It returns
+// the previous state and whether the state changed.
+func (s *Store[T]) changeState(item *T, state models.ResourceState, now time.Time) (*models.ResourceState, bool, error) {
+	var from *models.ResourceState
+	changed := false
+	updated, err := patchStatus(*item, func(status *Status) {
+		if status.State != nil && *status.State == state {
+			return
+		}
+		from, changed = status.State, true
+		status.State = &state
+
+		msg := fmt.Sprintf("%s is now in %s state", s.kind.Title, state)
+		reason := "stateChange"
+		status.Conditions = append(status.Conditions, models.StatusCondition{
+			LastTransitionAt: now,
+			Message:          &msg,
+			Reason:           &reason,
+			State:            state,
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	*item = updated
+	return from, changed, nil
+}
+
+// sortedItems returns the stored resources ordered by key. The caller must
+// hold the lock.
+func (s *Store[T]) sortedItems() []T {
+	items := make([]T, 0, len(s.items))
+	for _, key := range slices.Sorted(maps.Keys(s.items)) {
+		items = append(items, s.items[key])
+	}
+	return items
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/faults"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/timing"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+type widget struct {
+	Labels   map[string]string `json:"labels,omitempty"`
+	Metadata *Metadata         `json:"metadata,omitempty"`
+	Spec     widgetSpec        `json:"spec"`
+	Status   *Status           `json:"status,omitempty"`
+}
+
+type widgetSpec struct {
+	Size int `json:"size"`
+}
+
+var widgetKind = Kind{Name: "widget", Title: "Widget", Provider: "seca.test", Collection: "widgets"}
+
+// newTestStore returns a widget store with the default delays: creating after
+// 100ms, active after 600ms, updated after 500ms and deleted after 500ms.
+func newTestStore(t *testing.T) (*Store[widget], *clock.Manual) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewManual(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	s := New[widget](widgetKind, env.Env{
+		Clock:     clk,
+		Timings:   timing.Default(),
+		Faults:    faults.NewInjector(clk),
+		Integrity: integrity.NewRegistry(),
+	})
+	return s, clk
+}
+
+// serve calls handler with a request and returns the response. headers are
+// pairs of name and value.
+func serve(handler func(c *gin.Context), method, body string, headers ...string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(method, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		c.Request.Header.Set(headers[i], headers[i+1])
+	}
+	handler(c)
+	return rec
+}
+
+func put(s *Store[widget], tenant, name, body string, headers ...string) *httptest.ResponseRecorder {
+	return serve(func(c *gin.Context) { s.Put(c, Scope{Tenant: tenant, Name: name}) }, http.MethodPut, body, headers...)
+}
+
+func get(s *Store[widget], tenant, name string) *httptest.ResponseRecorder {
+	return serve(func(c *gin.Context) { s.Get(c, Scope{Tenant: tenant, Name: name}) }, http.MethodGet, "")
+}
+
+func del(s *Store[widget], tenant, name string) *httptest.ResponseRecorder {
+	return serve(func(c *gin.Context) { s.Delete(c, Scope{Tenant: tenant, Name: name}) }, http.MethodDelete, "")
+}
+
+func list(t *testing.T, s *Store[widget], tenant string, selector *string, limit *int) Iterator[widget] {
+	t.Helper()
+	rec := serve(func(c *gin.Context) { s.List(c, Scope{Tenant: tenant}, selector, limit, nil) }, http.MethodGet, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("List() = %d: %s", rec.Code, rec.Body)
+	}
+	var iterator Iterator[widget]
+	if err := json.Unmarshal(rec.Body.Bytes(), &iterator); err != nil {
+		t.Fatal(err)
+	}
+	return iterator
+}
+
+// expect checks the status code of rec and, for a resource, its state and
+// version.
+func expect(t *testing.T, rec *httptest.ResponseRecorder, code int, state models.ResourceState, version int64) {
+	t.Helper()
+	if rec.Code != code {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, code, rec.Body)
+	}
+	if state == "" {
+		return
+	}
+	var item widget
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Status == nil || item.Status.State == nil || *item.Status.State != state {
+		t.Fatalf("state = %+v, want %s", item.Status, state)
+	}
+	if item.Metadata.ResourceVersion != version {
+		t.Fatalf("resourceVersion = %d, want %d", item.Metadata.ResourceVersion, version)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	s, clk := newTestStore(t)
+
+	rec := put(s, "t1", "w1", `{"spec":{"size":1}}`)
+	expect(t, rec, http.StatusCreated, models.ResourceStatePending, 1)
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("ETag = %s, want \"1\"", etag)
+	}
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStatePending, 1)
+	clk.Advance(100 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateCreating, 1)
+	clk.Advance(500 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateActive, 1)
+
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":2}}`, "If-Match", `"1"`), http.StatusOK, models.ResourceStateUpdating, 2)
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":3}}`, "If-Match", `"1"`), http.StatusPreconditionFailed, "", 0)
+	clk.Advance(500 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateActive, 2)
+
+	iterator := list(t, s, "t1", nil, nil)
+	if len(iterator.Items) != 1 || iterator.Items[0].Spec.Size != 2 {
+		t.Fatalf("List() = %+v", iterator.Items)
+	}
+	if iterator.Metadata.Resource != "tenants/t1/widgets" {
+		t.Errorf("List() resource = %s", iterator.Metadata.Resource)
+	}
+
+	expect(t, del(s, "t1", "w1"), http.StatusAccepted, "", 0)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateDeleting, 2)
+	clk.Advance(500 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusNotFound, "", 0)
+	if items := list(t, s, "t1", nil, nil).Items; len(items) != 0 {
+		t.Fatalf("List() after delete = %+v", items)
+	}
+	expect(t, del(s, "t1", "w1"), http.StatusNotFound, "", 0)
+}
+
+func TestPutDeleting(t *testing.T) {
+	s, clk := newTestStore(t)
+	put(s, "t1", "w1", `{"spec":{"size":1}}`)
+	clk.Advance(time.Second)
+	expect(t, del(s, "t1", "w1"), http.StatusAccepted, "", 0)
+
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":2}}`), http.StatusConflict, "", 0)
+	modify := serve(func(c *gin.Context) {
+		s.Modify(c, Scope{Tenant: "t1", Name: "w1"}, func(*widget) (int, error) { return 0, nil })
+	}, http.MethodPost, "")
+	expect(t, modify, http.StatusConflict, "", 0)
+
+	// The deletion is not cancelled by the rejected writes.
+	clk.Advance(500 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusNotFound, "", 0)
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":2}}`), http.StatusCreated, models.ResourceStatePending, 1)
+}
+
+func TestPutOwnsMetadataAndStatus(t *testing.T) {
+	s, _ := newTestStore(t)
+	rec := put(s, "t1", "w1", `{"metadata":{"name":"other","tenant":"t2","resourceVersion":7},"spec":{"size":1},"status":{"state":"active"}}`)
+	expect(t, rec, http.StatusCreated, models.ResourceStatePending, 1)
+
+	var item widget
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{
+		ApiVersion:      "v1",
+		CreatedAt:       item.Metadata.CreatedAt,
+		Kind:            "widget",
+		LastModifiedAt:  item.Metadata.CreatedAt,
+		Name:            "w1",
+		Provider:        "seca.test",
+		Region:          Region,
+		Resource:        "tenants/t1/widgets/w1",
+		ResourceVersion: 1,
+		Tenant:          "t1",
+		Verb:            "put",
+	}
+	if *item.Metadata != want {
+		t.Errorf("metadata = %+v, want %+v", *item.Metadata, want)
+	}
+	expect(t, put(s, "t1", "w2", `{"spec":{"size":1}}`, "If-Match", `"1"`), http.StatusPreconditionFailed, "", 0)
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":1}}`, "If-None-Match", "*"), http.StatusPreconditionFailed, "", 0)
+	expect(t, put(s, "t1", "w1", `{"spec":`), http.StatusBadRequest, "", 0)
+}
+
+func TestStaleTransitionsAreDropped(t *testing.T) {
+	s, clk := newTestStore(t)
+	put(s, "t1", "w1", `{"spec":{"size":1}}`)
+	expect(t, put(s, "t1", "w1", `{"spec":{"size":2}}`), http.StatusOK, models.ResourceStateUpdating, 2)
+
+	// The creating transition of version 1 no longer applies.
+	clk.Advance(100 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateUpdating, 2)
+	clk.Advance(400 * time.Millisecond)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateActive, 2)
+	clk.Advance(time.Second)
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateActive, 2)
+}
+
+func TestList(t *testing.T) {
+	s, _ := newTestStore(t)
+	put(s, "t1", "c", `{"labels":{"env":"dev"},"spec":{"size":1}}`)
+	put(s, "t1", "a", `{"labels":{"env":"prod"},"spec":{"size":1}}`)
+	put(s, "t1", "b", `{"labels":{"env":"dev"},"spec":{"size":1}}`)
+	put(s, "t2", "d", `{"labels":{"env":"dev"},"spec":{"size":1}}`)
+
+	names := func(items []widget) string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Metadata.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	dev, two, invalid := "env=dev", 2, "env in"
+	tests := []struct {
+		name     string
+		tenant   string
+		selector *string
+		limit    *int
+		want     string
+		wantNext bool
+	}{
+		{name: "tenant", tenant: "t1", want: "a,b,c"},
+		{name: "other tenant", tenant: "t2", want: "d"},
+		{name: "selector", tenant: "t1", selector: &dev, want: "b,c"},
+		{name: "limit", tenant: "t1", limit: &two, want: "a,b", wantNext: true},
+		{name: "empty tenant", tenant: "t3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iterator := list(t, s, tt.tenant, tt.selector, tt.limit)
+			if got := names(iterator.Items); got != tt.want {
+				t.Errorf("List() = %s, want %s", got, tt.want)
+			}
+			if (iterator.Metadata.SkipToken != nil) != tt.wantNext {
+				t.Errorf("skipToken = %v, want next page %v", iterator.Metadata.SkipToken, tt.wantNext)
+			}
+		})
+	}
+
+	rec := serve(func(c *gin.Context) { s.List(c, Scope{Tenant: "t1"}, &invalid, nil, nil) }, http.MethodGet, "")
+	expect(t, rec, http.StatusBadRequest, "", 0)
+}
+
+func TestSeed(t *testing.T) {
+	s, _ := newTestStore(t)
+	var locked []bool
+	s.OnTransition = func(*widget, *models.ResourceState, models.ResourceState) {
+		if s.mu.TryLock() {
+			s.mu.Unlock()
+			locked = append(locked, false)
+			return
+		}
+		locked = append(locked, true)
+	}
+
+	err := s.Seed([]json.RawMessage{
+		json.RawMessage(`{"metadata":{"tenant":"t1","name":"w1"},"spec":{"size":1}}`),
+		json.RawMessage(`{"metadata":{"tenant":"t1"},"spec":{"size":1}}`),
+	})
+	if err == nil {
+		t.Fatal("Seed() of an item without name succeeded")
+	}
+	if items := s.Items(); len(items) != 0 || len(locked) != 0 {
+		t.Fatalf("failed Seed() added %+v", items)
+	}
+
+	err = s.Seed([]json.RawMessage{
+		json.RawMessage(`{"metadata":{"tenant":"t1","name":"w1"},"spec":{"size":1}}`),
+		json.RawMessage(`{"metadata":{"tenant":"t1","name":"w2"},"spec":{"size":1},"status":{"state":"creating"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, get(s, "t1", "w1"), http.StatusOK, models.ResourceStateActive, 1)
+	expect(t, get(s, "t1", "w2"), http.StatusOK, models.ResourceStateCreating, 1)
+	if len(locked) != 2 || !locked[0] || !locked[1] {
+		t.Fatalf("OnTransition called with the lock held = %v, want [true true]", locked)
+	}
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"cape-project.eu/mockserver/internal/labels"
+	"github.com/gin-gonic/gin"
+)
+
+// EventType is the kind of change a watch event reports.
+type EventType string
+
+const (
+	Created EventType = "created"
+	Updated EventType = "updated"
+	// StateChanged is a change of status.state by a state transition.
+	StateChanged EventType = "state"
+	Deleted      EventType = "deleted"
+)
+
+// Event is the data of a watch event.
+type Event struct {
+	Type            EventType       `json:"type"`
+	ResourceVersion int64           `json:"resourceVersion"`
+	Object          json.RawMessage `json:"object"`
+}
+
+// watchBuffer is the number of events a watcher may fall behind before its
+// stream is closed.
+const watchBuffer = 64
+
+const heartbeatInterval = 15 * time.Second
+
+type watcher struct {
+	scope  Scope
+	filter labels.Selector
+	events chan Event
+}
+
+// Watch streams the changes of the resources in the tenant or workspace of
+// scope that match the label selector as server-sent events, starting with a
+// created event per existing resource. The stream ends when the client goes
+// away, falls behind or the state is reset or restored.
+func (s *Store[T]) Watch(c *gin.Context, scope Scope, filter labels.Selector) {
+	w := &watcher{scope: scope, filter: filter, events: make(chan Event, watchBuffer)}
+
+	var initial []Event
+	if s.Static != nil {
+		for _, item := range s.Static(scope.Tenant) {
+			if event, ok := w.event(Created, item); ok {
+				initial = append(initial, event)
+			}
+		}
+	}
+	s.mu.Lock()
+	for _, item := range s.sortedItems() {
+		if event, ok := w.event(Created, item); ok {
+			initial = append(initial, event)
+		}
+	}
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+	defer s.unwatch(w)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	for _, event := range initial {
+		if !writeEvent(c, event) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-w.events:
+			if !ok || !writeEvent(c, event) {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(c *gin.Context, event Event) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("encoding watch event failed: %v", err)
+		return false
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err == nil
+}
+
+// event returns the event of type for item if the watcher selects it.
+func (w *watcher) event(typ EventType, item any) (Event, bool) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		log.Printf("encoding watch event failed: %v", err)
+		return Event{}, false
+	}
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil || e.Metadata == nil {
+		return Event{}, false
+	}
+	if e.Metadata.Tenant != w.scope.Tenant || e.Metadata.Workspace != w.scope.Workspace || !w.filter.Matches(e.Labels) {
+		return Event{}, false
+	}
+	return Event{Type: typ, ResourceVersion: e.Metadata.ResourceVersion, Object: data}, true
+}
+
+// publish sends an event for item to the watchers that select it. Watchers
+// that fell behind are closed. The caller must hold the lock.
+func (s *Store[T]) publish(typ EventType, item T) {
+	for w := range s.watchers {
+		event, ok := w.event(typ, item)
+		if !ok {
+			continue
+		}
+		select {
+		case w.events <- event:
+		default:
+			log.Printf("%s: closing watch of a client that fell behind", s.kind.Name)
+			s.closeWatcher(w)
+		}
+	}
+}
+
+// closeWatches ends all watches. The caller must hold the lock.
+func (s *Store[T]) closeWatches() {
+	for w := range s.watchers {
+		s.closeWatcher(w)
+	}
+}
+
+func (s *Store[T]) closeWatcher(w *watcher) {
+	delete(s.watchers, w)
+	close(w.events)
+}
+
+func (s *Store[T]) unwatch(w *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watchers[w]; ok {
+		s.closeWatcher(w)
+	}
+}
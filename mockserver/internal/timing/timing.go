@@ -0,0 +1,131 @@
+package timing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Delays of the state machine, measured from the request that started it.
+type Delays struct {
+	// Creating and Active are the delays until a new resource becomes
+	// creating and active.
+	Creating time.Duration
+	Active   time.Duration
+	// Update is the delay until an updated resource is active again.
+	Update time.Duration
+	// Delete is the delay until a deleting resource is removed.
+	Delete time.Duration
+	// Power is the delay of a power state change, e.g. from starting to on.
+	Power time.Duration
+}
+
+var DefaultDelays = Delays{
+	Creating: 100 * time.Millisecond,
+	Active:   600 * time.Millisecond,
+	Update:   500 * time.Millisecond,
+	Delete:   500 * time.Millisecond,
+	Power:    300 * time.Millisecond,
+}
+
+type Config struct {
+	Default Delays
+	// Kinds overrides the delays per resource kind, e.g. "instance".
+	Kinds map[string]Delays
+}
+
+func Default() Config {
+	return Config{Default: DefaultDelays, Kinds: map[string]Delays{}}
+}
+
+// Instant makes every state transition happen within the request.
+func Instant() Config {
+	return Config{Kinds: map[string]Delays{}}
+}
+
+func (c Config) For(kind string) Delays {
+	if delays, ok := c.Kinds[kind]; ok {
+		return delays
+	}
+	return c.Default
+}
+
+type delaysFile struct {
+	Creating string `yaml:"creating"`
+	Active   string `yaml:"active"`
+	Update   string `yaml:"update"`
+	Delete   string `yaml:"delete"`
+	Power    string `yaml:"power"`
+}
+
+type configFile struct {
+	Default delaysFile            `yaml:"default"`
+	Kinds   map[string]delaysFile `yaml:"kinds"`
+}
+
+// Load reads delays from a YAML file, e.g.
+//
+//	default: {creating: 100ms, active: 600ms}
+//	kinds:
+//	  instance: {active: 30s}
+//
+// Unset values fall back to the default delays. A resource becomes active
+// after it became creating, so a non-zero creating delay needs a longer
+// active delay.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var file configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	config := Default()
+	if config.Default, err = file.Default.apply(config.Default); err != nil {
+		return Config{}, fmt.Errorf("default: %w", err)
+	}
+	for kind, delays := range file.Kinds {
+		if config.Kinds[kind], err = delays.apply(config.Default); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", kind, err)
+		}
+	}
+	return config, nil
+}
+
+func (f delaysFile) apply(base Delays) (Delays, error) {
+	var err error
+	for _, field := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"creating", f.Creating, &base.Creating},
+		{"active", f.Active, &base.Active},
+		{"update", f.Update, &base.Update},
+		{"delete", f.Delete, &base.Delete},
+		{"power", f.Power, &base.Power},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if *field.into, err = time.ParseDuration(field.value); err != nil {
+			return Delays{}, fmt.Errorf("invalid %s delay %q: %w", field.name, field.value, err)
+		}
+		if *field.into < 0 {
+			return Delays{}, fmt.Errorf("%s delay must not be negative", field.name)
+		}
+	}
+	if base.Creating > 0 && base.Active <= base.Creating {
+		return Delays{}, fmt.Errorf("active delay %s must be longer than creating delay %s", base.Active, base.Creating)
+	}
+	return base, nil
+}
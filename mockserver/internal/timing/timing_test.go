@@ -0,0 +1,74 @@
+package timing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		want     Delays
+		wantKind Delays
+		wantErr  bool
+	}{
+		{
+			name:     "empty file",
+			file:     "",
+			want:     DefaultDelays,
+			wantKind: DefaultDelays,
+		},
+		{
+			name: "defaults and kind overrides",
+			file: "default: {creating: 1s, active: 2s}\nkinds:\n  instance: {active: 30s, power: 0s}\n",
+			want: Delays{Creating: time.Second, Active: 2 * time.Second, Update: DefaultDelays.Update, Delete: DefaultDelays.Delete, Power: DefaultDelays.Power},
+			wantKind: Delays{
+				Creating: time.Second, Active: 30 * time.Second, Update: DefaultDelays.Update, Delete: DefaultDelays.Delete,
+			},
+		},
+		{
+			name:     "instant",
+			file:     "default: {creating: 0s, active: 0s, update: 0s, delete: 0s, power: 0s}\n",
+			want:     Delays{},
+			wantKind: Delays{},
+		},
+		{name: "invalid duration", file: "default: {active: soon}\n", wantErr: true},
+		{name: "negative", file: "default: {delete: -1s}\n", wantErr: true},
+		{name: "active before creating", file: "default: {creating: 2s, active: 1s}\n", wantErr: true},
+		{name: "active with creating", file: "default: {creating: 1s, active: 1s}\n", wantErr: true},
+		// The kind inherits the default active delay of 600ms.
+		{name: "kind creating after default active", file: "kinds:\n  instance: {creating: 1s}\n", wantErr: true},
+		{name: "unknown field", file: "default: {actve: 1s}\n", wantErr: true},
+		{name: "invalid yaml", file: "default: [\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "timings.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Default != tt.want {
+				t.Errorf("Default = %+v, want %+v", got.Default, tt.want)
+			}
+			if got := got.For("instance"); got != tt.wantKind {
+				t.Errorf("For(instance) = %+v, want %+v", got, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("Load() succeeded, want error")
+	}
+}
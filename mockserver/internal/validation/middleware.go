@@ -0,0 +1,115 @@
+package validation
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+var requestOptions = &openapi3filter.Options{
+	MultiError:          true,
+	SkipSettingDefaults: true,
+	// Read-only properties like metadata and status are ignored by the
+	// handlers, so requests may echo them.
+	ExcludeReadOnlyValidations: true,
+	// Tokens are checked by the auth middleware.
+	AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+}
+
+var responseOptions = &openapi3filter.Options{
+	MultiError:            true,
+	IncludeResponseStatus: true,
+}
+
+// Middleware validates the requests of operations in the specs. In strict
+// mode successful responses are validated as well; error responses are not,
+// as the mockserver reports errors in its own format.
+func Middleware(validator *Validator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, ok := validator.route(c.Request, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = param.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    requestOptions,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			abort(c, requestProblem(c, err))
+			return
+		}
+		// Watches stream their events, which are not described in the specs.
+		if !validator.strict || c.Query("watch") == "true" {
+			c.Next()
+			return
+		}
+
+		recorder := &recorder{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		if recorder.status >= 200 && recorder.status < 300 {
+			err := openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 recorder.status,
+				Header:                 c.Writer.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+				Options:                responseOptions,
+			})
+			if err != nil {
+				log.Printf("response of %s %s does not match the spec: %v", c.Request.Method, c.Request.URL.Path, err)
+				c.Writer.Header().Del("ETag")
+				abort(c, responseProblem(c, err))
+				return
+			}
+		}
+		c.Writer.WriteHeader(recorder.status)
+		_, _ = c.Writer.Write(recorder.body.Bytes())
+	}
+}
+
+// recorder holds back the response until it is validated.
+type recorder struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *recorder) WriteHeaderNow() {}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *recorder) Status() int {
+	return r.status
+}
+
+func (r *recorder) Size() int {
+	return r.body.Len()
+}
+
+func (r *recorder) Written() bool {
+	return false
+}
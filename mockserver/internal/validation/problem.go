@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// Problem is an RFC 9457 problem document. Sources point at the invalid
+// parts of the request or response, like the errors of the SecAPI.
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail"`
+	Instance string   `json:"instance,omitempty"`
+	Sources  []Source `json:"sources,omitempty"`
+}
+
+// Source is either a JSON pointer into the body or a parameter name.
+type Source struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+func newProblem(status int, instance, detail string, sources []Source) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+		Sources:  sources,
+	}
+}
+
+func abort(c *gin.Context, problem Problem) {
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// requestProblem responds 422 if only the body violates its schema and 400
+// for invalid parameters or bodies that cannot be parsed.
+func requestProblem(c *gin.Context, err error) Problem {
+	status := http.StatusUnprocessableEntity
+	var sources []Source
+	for _, err := range flatten(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(err, &requestErr) {
+			status = http.StatusBadRequest
+			sources = append(sources, Source{Detail: err.Error()})
+			continue
+		}
+		schemaErrs := schemaErrors(requestErr.Err)
+		if requestErr.Parameter != nil {
+			status = http.StatusBadRequest
+			detail := reason(requestErr.Reason, requestErr.Err)
+			if len(schemaErrs) > 0 {
+				detail = schemaErrs[0].Reason
+			}
+			sources = append(sources, Source{Parameter: requestErr.Parameter.Name, Detail: detail})
+			continue
+		}
+		if len(schemaErrs) == 0 {
+			status = http.StatusBadRequest
+			sources = append(sources, Source{Detail: reason(requestErr.Reason, requestErr.Err)})
+			continue
+		}
+		sources = append(sources, schemaSources(schemaErrs)...)
+	}
+
+	detail := "the request body does not match the spec"
+	if status == http.StatusBadRequest {
+		detail = "the request does not match the spec"
+	}
+	return newProblem(status, c.Request.URL.Path, detail, sources)
+}
+
+func responseProblem(c *gin.Context, err error) Problem {
+	var sources []Source
+	for _, err := range flatten(err) {
+		if schemaErrs := schemaErrors(err); len(schemaErrs) > 0 {
+			sources = append(sources, schemaSources(schemaErrs)...)
+			continue
+		}
+		sources = append(sources, Source{Detail: err.Error()})
+	}
+	return newProblem(http.StatusInternalServerError, c.Request.URL.Path, "the mockserver response does not match the spec", sources)
+}
+
+func schemaSources(errs []*openapi3.SchemaError) []Source {
+	sources := make([]Source, 0, len(errs))
+	for _, err := range errs {
+		sources = append(sources, Source{Pointer: pointer(err.JSONPointer()), Detail: err.Reason})
+	}
+	return sources
+}
+
+// pointer escapes path as a JSON pointer, e.g. /spec/sizeGB. The pointer to
+// the whole document is empty.
+func pointer(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// flatten splits multi errors, without unwrapping other errors.
+func flatten(err error) []error {
+	multi, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range multi {
+		errs = append(errs, flatten(err)...)
+	}
+	return errs
+}
+
+func schemaErrors(err error) []*openapi3.SchemaError {
+	var errs []*openapi3.SchemaError
+	for _, err := range flatten(err) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			errs = append(errs, schemaErr)
+		}
+	}
+	return errs
+}
+
+func reason(reason string, err error) string {
+	switch {
+	case err == nil:
+		return reason
+	case reason == "" || reason == err.Error():
+		return err.Error()
+	default:
+		return reason + ": " + err.Error()
+	}
+}
@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+type Mode string
+
+const (
+	Off Mode = "off"
+	// Requests rejects requests that do not match the specs.
+	Requests Mode = "requests"
+	// Strict also fails responses of the mockserver that do not match the
+	// specs with 500.
+	Strict Mode = "strict"
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case Off, Requests, Strict:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid validation mode %q, expected off, requests or strict", value)
+	}
+}
+
+// Validator checks requests, and in strict mode responses, of the provider
+// servers against the operations of the specs.
+type Validator struct {
+	routes map[string]*routers.Route
+	strict bool
+}
+
+var (
+	loadedMu sync.Mutex
+	// loaded caches the routes per spec dir, as specs are expensive to load
+	// and the in-process mockservers of tests share them.
+	loaded = map[string]map[string]*routers.Route{}
+)
+
+// Load reads the specs in specDir, e.g. foundation.compute.v1.yaml is served
+// at /providers/seca.compute like the generated servers.
+func Load(specDir string, mode Mode) (*Validator, error) {
+	if specDir == "" {
+		return nil, errors.New("no spec dir given")
+	}
+	dir, err := filepath.Abs(specDir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("spec dir %s not found", dir)
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	routes, ok := loaded[dir]
+	if !ok {
+		if routes, err = loadRoutes(dir); err != nil {
+			return nil, err
+		}
+		loaded[dir] = routes
+	}
+	return &Validator{routes: routes, strict: mode == Strict}, nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func loadRoutes(dir string) (map[string]*routers.Route, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := map[string]*routers.Route{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".yaml" {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(name, ".yaml"), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		baseURL := "/providers/seca." + parts[len(parts)-2]
+
+		loader := openapi3.NewLoader()
+		loader.IsExternalRefsAllowed = true
+		doc, err := loader.LoadFromFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", name, err)
+		}
+		for path, item := range doc.Paths.Map() {
+			ginPath := baseURL + pathParam.ReplaceAllString(path, ":$1")
+			for method, operation := range item.Operations() {
+				routes[routeKey(method, ginPath)] = &routers.Route{
+					Spec:      doc,
+					Path:      path,
+					PathItem:  item,
+					Method:    method,
+					Operation: operation,
+				}
+			}
+		}
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no specs in %s", dir)
+	}
+	return routes, nil
+}
+
+func routeKey(method, ginPath string) string {
+	return strings.ToUpper(method) + " " + ginPath
+}
+
+func (v *Validator) route(r *http.Request, ginPath string) (*routers.Route, bool) {
+	route, ok := v.routes[routeKey(r.Method, ginPath)]
+	return route, ok
+}
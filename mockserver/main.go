@@ -10,35 +10,87 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
-	c_v1 "cape-project.eu/mockserver/foundation/compute/v1"
-	s_v1 "cape-project.eu/mockserver/foundation/storage/v1"
-	ws_v1 "cape-project.eu/mockserver/foundation/workspace/v1"
-	"github.com/gin-gonic/gin"
+	"cape-project.eu/mockserver/pkg/mockserver"
 )
 
 func main() {
 	var port int
+	var dataDir string
+	var persistInterval time.Duration
+	var fixturePath string
+	var clockMode string
+	var timingsPath string
+	var instant bool
+	var faultsPath string
+	var instanceSKUsPath string
+	var authPath string
+	var validationMode string
+	var specDir string
 	flag.IntVar(&port, "port", resolvePort(), "server port")
+	flag.StringVar(&dataDir, "data-dir", os.Getenv("DATA_DIR"), "directory to persist the state to, in-memory only if empty")
+	flag.DurationVar(&persistInterval, "persist-interval", time.Second, "interval to write state changes to the data dir")
+	flag.StringVar(&fixturePath, "fixture", os.Getenv("FIXTURE"), "YAML or JSON fixture to seed on start")
+	flag.StringVar(&clockMode, "clock", envOr("CLOCK", "real"), "clock driving state transitions, real or manual (advanced via /admin/clock/advance)")
+	flag.StringVar(&timingsPath, "timings", os.Getenv("TIMINGS"), "YAML file with state transition delays per resource kind")
+	flag.BoolVar(&instant, "instant", os.Getenv("INSTANT") == "true", "complete all state transitions within the request")
+	flag.StringVar(&faultsPath, "faults", os.Getenv("FAULTS"), "YAML or JSON file with fault injection rules")
+	flag.StringVar(&instanceSKUsPath, "instance-skus", os.Getenv("INSTANCE_SKUS"), "YAML or JSON file replacing the compute SKU catalog")
+	flag.StringVar(&authPath, "auth", os.Getenv("AUTH"), "YAML or JSON file with bearer tokens and roles, requires a token on every provider and admin request")
+	flag.StringVar(&validationMode, "validate", envOr("VALIDATE", "off"), "validate against the specs: off, requests or strict (responses as well)")
+	flag.StringVar(&specDir, "spec-dir", envOr("SPEC_DIR", "../ext/secapi/spec"), "directory with the SecAPI specs to validate against")
 	flag.Parse()
 
-	router := gin.Default()
+	var manualClock bool
+	switch clockMode {
+	case "real":
+	case "manual":
+		manualClock = true
+	default:
+		log.Fatalf("invalid clock %q, expected real or manual", clockMode)
+	}
+
+	srv, err := mockserver.New(mockserver.Options{
+		ManualClock:      manualClock,
+		Instant:          instant,
+		TimingsFile:      timingsPath,
+		FaultsFile:       faultsPath,
+		InstanceSKUsFile: instanceSKUsPath,
+		AuthFile:         authPath,
+		Validation:       validationMode,
+		SpecDir:          specDir,
+		DataDir:          dataDir,
+		FixtureFile:      fixturePath,
+		RequestLog:       true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if dataDir != "" {
+		log.Printf("persisting state to %s", dataDir)
+	}
 
-	ws_v1.RegisterServer(router)
-	s_v1.RegisterServer(router)
-	c_v1.RegisterServer(router)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	addr := net.JoinHostPort("", strconv.Itoa(port))
 	server := &http.Server{
 		Addr:              addr,
-		Handler:           router,
+		Handler:           srv,
 		ReadHeaderTimeout: 5 * time.Second,
+		// Ends open watches on shutdown.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	var persisted sync.WaitGroup
+	if dataDir != "" {
+		persisted.Go(func() {
+			srv.Persist(ctx, persistInterval)
+		})
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -54,6 +106,7 @@ func main() {
 	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("server failed: %v", err)
 	}
+	persisted.Wait()
 }
 
 func resolvePort() int {
@@ -72,3 +125,10 @@ func resolvePort() int {
 
 	return port
 }
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,272 @@
+// Package mockserver serves the SecAPI mock in-process. Package
+// mockservertest starts it for Go tests:
+//
+//	srv := mockservertest.NewServer(t, mockserver.Options{Instant: true})
+//	client := secapi.NewClient(srv.URL)
+//
+// Every Server has its own state, so tests can run in parallel.
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"cape-project.eu/mockserver/internal/admin"
+	"cape-project.eu/mockserver/internal/auth"
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/clock"
+	"cape-project.eu/mockserver/internal/env"
+	"cape-project.eu/mockserver/internal/faults"
+	"cape-project.eu/mockserver/internal/integrity"
+	"cape-project.eu/mockserver/internal/state"
+	"cape-project.eu/mockserver/internal/timing"
+	"cape-project.eu/mockserver/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Options configure a Server. The file options take the formats of the
+// corresponding command line flags.
+type Options struct {
+	// ManualClock makes time only move on Advance or /admin/clock/advance.
+	ManualClock bool
+	// Instant completes all state transitions within the request.
+	Instant bool
+	// TimingsFile sets the state transition delays per resource kind.
+	TimingsFile string
+	// FaultsFile adds fault injection rules.
+	FaultsFile string
+	// InstanceSKUsFile replaces the compute SKU catalog.
+	InstanceSKUsFile string
+	// Auth requires a bearer token on provider and admin requests, a static
+	// token of AuthFile or a JWT issued by Token or POST /admin/tokens. Admin
+	// requests need a token with the admin role.
+	Auth     bool
+	AuthFile string
+	// Validation is off (default), requests or strict: requests that do not
+	// match the specs in SpecDir are rejected with 400 or 422, in strict mode
+	// responses that do not match fail with 500.
+	Validation string
+	SpecDir    string
+	// DataDir loads the state persisted there by Persist.
+	DataDir string
+	// FixtureFile is seeded after the state is loaded.
+	FixtureFile string
+	// RequestLog logs every request.
+	RequestLog bool
+}
+
+// Server is a mockserver with all provider servers and the /admin endpoints.
+type Server struct {
+	router  *gin.Engine
+	env     env.Env
+	dataDir string
+}
+
+func New(opts Options) (*Server, error) {
+	var clk clock.Clock = clock.Real{}
+	if opts.ManualClock {
+		clk = clock.NewManual(time.Now())
+	}
+
+	timings := timing.Default()
+	switch {
+	case opts.Instant && opts.TimingsFile != "":
+		return nil, errors.New("instant and timings are mutually exclusive")
+	case opts.Instant:
+		timings = timing.Instant()
+	case opts.TimingsFile != "":
+		var err error
+		if timings, err = timing.Load(opts.TimingsFile); err != nil {
+			return nil, fmt.Errorf("loading timings failed: %w", err)
+		}
+	}
+
+	injector := faults.NewInjector(clk)
+	if opts.FaultsFile != "" {
+		rules, err := faults.ReadRules(opts.FaultsFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading fault rules failed: %w", err)
+		}
+		if _, err := injector.Add(rules...); err != nil {
+			return nil, fmt.Errorf("adding fault rules failed: %w", err)
+		}
+	}
+
+	var instanceSKUs []catalog.InstanceSKU
+	if opts.InstanceSKUsFile != "" {
+		var err error
+		if instanceSKUs, err = catalog.ReadInstanceSKUs(opts.InstanceSKUsFile); err != nil {
+			return nil, fmt.Errorf("reading instance SKUs failed: %w", err)
+		}
+	}
+
+	var authenticator *auth.Authenticator
+	if opts.Auth || opts.AuthFile != "" {
+		var config auth.Config
+		var err error
+		if opts.AuthFile != "" {
+			if config, err = auth.ReadConfig(opts.AuthFile); err != nil {
+				return nil, fmt.Errorf("reading auth config failed: %w", err)
+			}
+		}
+		if authenticator, err = auth.New(config, clk); err != nil {
+			return nil, err
+		}
+	}
+
+	var validator *validation.Validator
+	if opts.Validation != "" {
+		mode, err := validation.ParseMode(opts.Validation)
+		if err != nil {
+			return nil, err
+		}
+		if mode != validation.Off {
+			if validator, err = validation.Load(opts.SpecDir, mode); err != nil {
+				return nil, fmt.Errorf("loading specs for validation failed, set the spec dir or turn validation off: %w", err)
+			}
+		}
+	}
+
+	router := gin.New()
+	if opts.RequestLog {
+		router.Use(gin.Logger())
+	}
+	router.Use(gin.Recovery(), faults.Middleware(injector, "/admin"))
+	if authenticator != nil {
+		router.Use(auth.Middleware(authenticator, "/admin"))
+		router.GET(auth.JWKSPath, auth.KeySet(authenticator))
+	}
+	if validator != nil {
+		router.Use(validation.Middleware(validator))
+	}
+	s := &Server{
+		router: router,
+		env: env.Env{
+			Registry:  state.NewRegistry(),
+			Clock:     clk,
+			Timings:   timings,
+			Faults:    injector,
+			Integrity: integrity.NewRegistry(),
+			Auth:      authenticator,
+
+			InstanceSKUs: instanceSKUs,
+		},
+		dataDir: opts.DataDir,
+	}
+	registerServers(router, s.env)
+	admin.RegisterRoutes(router, s.env)
+
+	if opts.DataDir != "" {
+		if err := os.MkdirAll(opts.DataDir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating data dir failed: %w", err)
+		}
+		if err := s.env.Registry.Load(opts.DataDir); err != nil {
+			return nil, fmt.Errorf("loading state from %s failed: %w", opts.DataDir, err)
+		}
+	}
+	if opts.FixtureFile != "" {
+		fixture, err := state.ReadFixture(opts.FixtureFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading fixture failed: %w", err)
+		}
+		if err := s.env.Registry.Seed(fixture); err != nil {
+			return nil, fmt.Errorf("seeding fixture failed: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Persist saves the state to the data dir every interval until ctx is done.
+// It returns right away without a data dir.
+func (s *Server) Persist(ctx context.Context, interval time.Duration) {
+	if s.dataDir == "" {
+		return
+	}
+	s.env.Registry.Persist(ctx, s.dataDir, interval)
+}
+
+// Reset removes all resources.
+func (s *Server) Reset() {
+	s.env.Registry.Reset()
+}
+
+// Seed adds the resources of a YAML or JSON fixture, see POST /admin/fixtures.
+func (s *Server) Seed(fixture []byte) error {
+	parsed, err := state.ParseFixture(fixture)
+	if err != nil {
+		return err
+	}
+	return s.env.Registry.Seed(parsed)
+}
+
+// Dump returns all resources as a JSON fixture, the format accepted by Seed.
+func (s *Server) Dump() ([]byte, error) {
+	fixture, err := s.env.Registry.Dump()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fixture)
+}
+
+// Snapshot returns the full state in the format of GET /admin/snapshot.
+func (s *Server) Snapshot() ([]byte, error) {
+	snapshot, err := s.env.Registry.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot)
+}
+
+// Restore replaces the state with a snapshot taken by Snapshot.
+func (s *Server) Restore(snapshot []byte) error {
+	var parsed state.Snapshot
+	if err := json.Unmarshal(snapshot, &parsed); err != nil {
+		return err
+	}
+	return s.env.Registry.Restore(parsed)
+}
+
+// AddFaults adds a YAML or JSON list of fault injection rules.
+func (s *Server) AddFaults(rules []byte) error {
+	parsed, err := faults.ParseRules(rules)
+	if err != nil {
+		return err
+	}
+	_, err = s.env.Faults.Add(parsed...)
+	return err
+}
+
+// ClearFaults removes all fault injection rules.
+func (s *Server) ClearFaults() {
+	s.env.Faults.Clear()
+}
+
+// Token issues a JWT for subject with access to tenants ("*" for all) in
+// roles, e.g. viewer or editor, that expires after ttl.
+func (s *Server) Token(subject string, tenants, roles []string, ttl time.Duration) (string, error) {
+	if s.env.Auth == nil {
+		return "", errors.New("auth is not enabled")
+	}
+	token, _, err := s.env.Auth.Issue(auth.Principal{Subject: subject, Tenants: tenants, Roles: roles}, ttl)
+	return token, err
+}
+
+// Advance moves the manual clock forward by d and runs the state transitions
+// that are due.
+func (s *Server) Advance(d time.Duration) error {
+	manual, ok := s.env.Clock.(*clock.Manual)
+	if !ok {
+		return errors.New("the clock is not manual")
+	}
+	manual.Advance(d)
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package mockservertest runs the mockserver in-process for Go tests.
+package mockservertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cape-project.eu/mockserver/pkg/mockserver"
+)
+
+// Server is a mockserver listening on a local port.
+type Server struct {
+	*mockserver.Server
+	// URL is the base URL of the server, e.g. http://127.0.0.1:41234.
+	URL    string
+	Client *http.Client
+}
+
+// NewServer starts a mockserver that is closed when the test ends. It fails
+// the test if the server cannot be created.
+func NewServer(tb testing.TB, opts mockserver.Options) *Server {
+	tb.Helper()
+	srv, err := mockserver.New(opts)
+	if err != nil {
+		tb.Fatalf("starting mockserver failed: %v", err)
+	}
+	httpServer := httptest.NewServer(srv)
+	tb.Cleanup(func() {
+		// Ends open watches, Close waits for them otherwise.
+		httpServer.CloseClientConnections()
+		httpServer.Close()
+	})
+	return &Server{Server: srv, URL: httpServer.URL, Client: httpServer.Client()}
+}
@@ -0,0 +1,61 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cape-project.eu/mockserver/internal/store"
+)
+
+// Collection gives typed access to the resources of one kind, bypassing the
+// API and its checks. Workspace is empty for tenant level resources.
+type Collection[T any] struct {
+	store *store.Store[T]
+}
+
+// Resources returns the collection holding resources of type T, e.g.
+// models.Instance. It panics if no server stores T.
+func Resources[T any](s *Server) *Collection[T] {
+	for _, registered := range s.env.Registry.Stores() {
+		group, ok := registered.(*store.Group)
+		if !ok {
+			continue
+		}
+		for _, collection := range group.Collections() {
+			if typed, ok := collection.(*store.Store[T]); ok {
+				return &Collection[T]{store: typed}
+			}
+		}
+	}
+	var zero T
+	panic(fmt.Sprintf("mockserver: no store holds %T", zero))
+}
+
+// Get returns the resource, including the public resources every tenant sees.
+func (c *Collection[T]) Get(tenant, workspace, name string) (T, bool) {
+	return c.store.Find(store.Scope{Tenant: tenant, Workspace: workspace, Name: name})
+}
+
+// List returns all resources ordered by tenant, workspace and name.
+func (c *Collection[T]) List() []T {
+	return c.store.Items()
+}
+
+// Put adds or replaces resources like a fixture: metadata derived from the
+// path is filled in and a missing state defaults to active.
+func (c *Collection[T]) Put(items ...T) error {
+	raw := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, data)
+	}
+	return c.store.Seed(raw)
+}
+
+// Delete removes the resource right away.
+func (c *Collection[T]) Delete(tenant, workspace, name string) bool {
+	return c.store.Remove(store.Scope{Tenant: tenant, Workspace: workspace, Name: name})
+}
@@ -60,11 +60,20 @@ func main() {
 		specRoot = filepath.Join(cwd, specRoot)
 	}
 
-	resources, err := codegen.LoadControlResources(controlPath)
+	discovery, err := codegen.Discover(controlPath, specRoot)
 	if err != nil {
 		fmt.Printf("error reading control resources: %v\n", err)
 		return
 	}
+	resources, functions := discovery.Resources, discovery.GetterFunctions
+	// packages with getter functions only need a provider prefix as well
+	for packageName, packageFunctions := range functions {
+		for name, function := range packageFunctions {
+			if _, ok := resources[name]; !ok {
+				resources[name] = codegen.ControlResourceSpec{Package: packageName, APIPackage: function.APIPackage}
+			}
+		}
+	}
 
 	resourceNames := make([]string, 0, len(resources))
 	for name := range resources {
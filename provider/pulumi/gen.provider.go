@@ -12,6 +12,7 @@ import (
 )
 
 const PulumiControlResourceFile = "pulumi.gen.yaml"
+const SpecDir = "../../ext/secapi/spec"
 const ProviderTemplatePath = "internal/codegen/provider.tmpl"
 const PulumiPluginTemplatePath = "internal/codegen/pulumi_plugin.tmpl"
 const ResourceImportBase = "cape-project.eu/provider/pulumi/internal"
@@ -26,11 +27,22 @@ func main() {
 		controlPath = filepath.Join(cwd, controlPath)
 	}
 
+	specRoot := SpecDir
+	if !filepath.IsAbs(specRoot) {
+		specRoot = filepath.Join(cwd, specRoot)
+	}
+
 	genYaml, err := codegen.GetPulumiGenYaml(controlPath)
 	if err != nil {
 		fmt.Printf("error reading control resources: %v\n", err)
 		return
 	}
+	discovery, err := codegen.Discover(controlPath, specRoot)
+	if err != nil {
+		fmt.Printf("error discovering resources: %v\n", err)
+		return
+	}
+	genYaml.Resources, genYaml.GetterFunctions = discovery.Resources, discovery.GetterFunctions
 
 	writeTemplate(filepath.Join(cwd, "provider.gen.go"), genYaml, providerTemplate)
 	writeTemplate(filepath.Join(cwd, "PulumiPlugin.yaml"), genYaml, pulumiPluginTemplate)
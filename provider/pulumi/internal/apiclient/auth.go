@@ -0,0 +1,62 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// AuthError is an APIError for a rejected token (401) or a forbidden
+// operation (403).
+type AuthError struct {
+	*APIError
+}
+
+func (e *AuthError) Error() string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Sprintf("authentication failed (%d): the configured authToken is missing, invalid or expired: %s", e.StatusCode, e.message())
+	case http.StatusForbidden:
+		return fmt.Sprintf("authorization failed (%d): the configured authToken is not allowed to perform this operation: %s", e.StatusCode, e.message())
+	default:
+		return fmt.Sprintf("auth error (%d): %s", e.StatusCode, e.message())
+	}
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.APIError
+}
+
+// BearerToken returns a request editor attaching the token as Authorization header.
+// A nil or blank token leaves the request untouched.
+func BearerToken(token *string) func(ctx context.Context, req *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		if token == nil {
+			return nil
+		}
+		value := strings.TrimSpace(*token)
+		if value == "" {
+			return nil
+		}
+		req.Header.Set("Authorization", "Bearer "+value)
+		return nil
+	}
+}
+
+func CheckAuth(statusCode int, body []byte) error {
+	return checkAuth(NewAPIError(statusCode, body))
+}
+
+func checkAuth(apiErr *APIError) error {
+	if apiErr.StatusCode != http.StatusUnauthorized && apiErr.StatusCode != http.StatusForbidden {
+		return nil
+	}
+	return &AuthError{APIError: apiErr}
+}
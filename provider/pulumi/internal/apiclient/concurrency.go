@@ -0,0 +1,73 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrConflict = errors.New("conflict")
+
+// ConflictError is an APIError for a write that failed because the resource
+// was modified concurrently.
+type ConflictError struct {
+	*APIError
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("resource was modified concurrently (%d), refresh the stack and retry: %s", e.StatusCode, e.message())
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.APIError
+}
+
+// IfMatch returns a request editor that makes the write conditional on the
+// given resource version. The request fails for unknown versions (<= 0)
+// instead of being sent without precondition.
+func IfMatch(resourceVersion int64) func(ctx context.Context, req *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		if resourceVersion <= 0 {
+			return fmt.Errorf("unknown resource version %d", resourceVersion)
+		}
+		req.Header.Set("If-Match", strconv.Quote(strconv.FormatInt(resourceVersion, 10)))
+		return nil
+	}
+}
+
+// IfNoneMatch returns a request editor that makes the write fail if the
+// resource already exists.
+func IfNoneMatch() func(ctx context.Context, req *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		req.Header.Set("If-None-Match", "*")
+		return nil
+	}
+}
+
+// CheckConflict returns a *ConflictError if a write failed because the
+// resource was modified concurrently: a failed precondition (412) or a 409
+// reporting a version mismatch. Other conflicts, e.g. deleting a referenced
+// resource, are not resolved by a refresh and are left to CheckResponse.
+func CheckConflict(statusCode int, body []byte) error {
+	return checkConflict(NewAPIError(statusCode, body))
+}
+
+func checkConflict(apiErr *APIError) error {
+	switch {
+	case apiErr.StatusCode == http.StatusPreconditionFailed:
+	case apiErr.StatusCode == http.StatusConflict && versionMismatch(apiErr):
+	default:
+		return nil
+	}
+	return &ConflictError{APIError: apiErr}
+}
+
+// versionMismatch reports whether the problem of apiErr is a resource version
+// mismatch.
+func versionMismatch(apiErr *APIError) bool {
+	problem := strings.ToLower(apiErr.Type + " " + apiErr.Title + " " + apiErr.Detail)
+	return strings.Contains(problem, "version mismatch")
+}
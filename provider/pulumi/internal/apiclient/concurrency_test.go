@@ -0,0 +1,75 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		version int64
+		want    string
+		wantErr bool
+	}{
+		{version: 3, want: `"3"`},
+		{version: 0, wantErr: true},
+		{version: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPut, "http://localhost/", nil)
+		err := IfMatch(tt.version)(context.Background(), req)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("IfMatch(%d) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+		if got := req.Header.Get("If-Match"); got != tt.want {
+			t.Errorf("IfMatch(%d) header = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost/", nil)
+	if err := IfNoneMatch()(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("If-None-Match"); got != "*" {
+		t.Errorf("If-None-Match = %q, want *", got)
+	}
+}
+
+func TestCheckConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		conflict bool
+	}{
+		{name: "precondition failed", status: http.StatusPreconditionFailed, body: `{"error":"resource version mismatch, current version is 3"}`, conflict: true},
+		{name: "version mismatch", status: http.StatusConflict, body: `{"type":"about:blank","title":"Conflict","detail":"resource version mismatch"}`, conflict: true},
+		{name: "referenced resource", status: http.StatusConflict, body: `{"error":"workspace t1/w1 is still referenced by instance t1/w1/vm1"}`},
+		{name: "read-only resource", status: http.StatusConflict, body: `{"error":"image ubuntu-24.04-amd64 is read-only"}`},
+		{name: "power action", status: http.StatusConflict, body: `{"error":"instance power state is off, expected on"}`},
+		{name: "ok", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckConflict(tt.status, []byte(tt.body))
+			var conflictErr *ConflictError
+			if got := errors.As(err, &conflictErr); got != tt.conflict {
+				t.Fatalf("CheckConflict() = %v, want conflict %v", err, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestCheckResponseConflict(t *testing.T) {
+	err := CheckResponse(http.StatusConflict, []byte(`{"error":"image ubuntu-24.04-amd64 is read-only"}`), http.StatusOK)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("CheckResponse() = %v, want ErrConflict", err)
+	}
+	if want := "conflict error (409): image ubuntu-24.04-amd64 is read-only"; err.Error() != want {
+		t.Errorf("CheckResponse() = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,252 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+type Category string
+
+const (
+	CategoryNotFound   Category = "not found"
+	CategoryConflict   Category = "conflict"
+	CategoryValidation Category = "validation"
+	CategoryQuota      Category = "quota"
+	CategoryAuth       Category = "auth"
+	CategoryTransient  Category = "transient"
+	CategoryUnknown    Category = "unknown"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	ErrQuota      = errors.New("quota exceeded")
+	ErrTransient  = errors.New("transient failure")
+)
+
+type FieldError struct {
+	// Path is the Pulumi property path, e.g. spec.sizeGB.
+	Path    string
+	Message string
+}
+
+// APIError is a failed SecAPI call. It is decoded from the SecAPI error
+// schema (problem details) or the plain {"error": "..."} body of the
+// mockserver.
+type APIError struct {
+	StatusCode int
+	Category   Category
+	Type       string
+	Title      string
+	Detail     string
+	Fields     []FieldError
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s error (%d)", e.Category, e.StatusCode)
+	if msg := e.message(); msg != "" {
+		b.WriteString(": " + msg)
+	}
+	for _, field := range e.Fields {
+		fmt.Fprintf(&b, "; %s: %s", field.Path, field.Message)
+	}
+	return b.String()
+}
+
+func (e *APIError) message() string {
+	switch {
+	case e.Title != "" && e.Detail != "" && e.Title != e.Detail:
+		return e.Title + ": " + e.Detail
+	case e.Detail != "":
+		return e.Detail
+	case e.Title != "":
+		return e.Title
+	default:
+		return e.Body
+	}
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.Category {
+	case CategoryNotFound:
+		return ErrNotFound
+	case CategoryConflict:
+		return ErrConflict
+	case CategoryValidation:
+		return ErrValidation
+	case CategoryQuota:
+		return ErrQuota
+	case CategoryTransient:
+		return ErrTransient
+	case CategoryAuth:
+		if e.StatusCode == http.StatusForbidden {
+			return ErrForbidden
+		}
+		return ErrUnauthorized
+	default:
+		return nil
+	}
+}
+
+type problemDetails struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Detail   string          `json:"detail"`
+	Error    string          `json:"error"`
+	Message  string          `json:"message"`
+	Sources  []problemSource `json:"sources"`
+	Errors   []problemSource `json:"errors"`
+	Instance string          `json:"instance"`
+}
+
+type problemSource struct {
+	Pointer   string `json:"pointer"`
+	Parameter string `json:"parameter"`
+	Field     string `json:"field"`
+	Path      string `json:"path"`
+	Detail    string `json:"detail"`
+	Message   string `json:"message"`
+}
+
+func NewAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+
+	var problem problemDetails
+	if err := json.Unmarshal(body, &problem); err == nil {
+		apiErr.Type = problem.Type
+		apiErr.Title = problem.Title
+		apiErr.Detail = firstNonEmpty(problem.Detail, problem.Error, problem.Message)
+		for _, source := range append(problem.Sources, problem.Errors...) {
+			path := PropertyPath(firstNonEmpty(source.Pointer, source.Field, source.Path, source.Parameter))
+			message := firstNonEmpty(source.Detail, source.Message, apiErr.Detail)
+			if path == "" && message == "" {
+				continue
+			}
+			apiErr.Fields = append(apiErr.Fields, FieldError{Path: path, Message: message})
+		}
+	}
+	apiErr.Category = categorize(statusCode, apiErr.Type+" "+apiErr.Title)
+	return apiErr
+}
+
+func categorize(statusCode int, problemType string) Category {
+	if strings.Contains(strings.ToLower(problemType), "quota") {
+		return CategoryQuota
+	}
+	switch {
+	case statusCode == http.StatusNotFound, statusCode == http.StatusGone:
+		return CategoryNotFound
+	case statusCode == http.StatusConflict, statusCode == http.StatusPreconditionFailed:
+		return CategoryConflict
+	case statusCode == http.StatusBadRequest, statusCode == http.StatusUnprocessableEntity:
+		return CategoryValidation
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return CategoryAuth
+	case statusCode == http.StatusTooManyRequests, statusCode == http.StatusRequestTimeout, statusCode >= 500:
+		return CategoryTransient
+	default:
+		return CategoryUnknown
+	}
+}
+
+// PropertyPath turns a JSON pointer (/spec/sizeGB) into a Pulumi property
+// path (spec.sizeGB). Array indices become [n].
+func PropertyPath(pointer string) string {
+	pointer = strings.TrimPrefix(strings.TrimPrefix(pointer, "#"), "/")
+	if pointer == "" || !strings.Contains(pointer, "/") {
+		return pointer
+	}
+
+	var b strings.Builder
+	for i, segment := range strings.Split(pointer, "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		if isIndex(segment) {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// CheckResponse returns nil if statusCode is one of expected. Otherwise it
+// returns an *AuthError, *ConflictError or *APIError describing the failure,
+// the former two wrap the *APIError.
+func CheckResponse(statusCode int, body []byte, expected ...int) error {
+	if slices.Contains(expected, statusCode) {
+		return nil
+	}
+	apiErr := NewAPIError(statusCode, body)
+	if err := checkAuth(apiErr); err != nil {
+		return err
+	}
+	if err := checkConflict(apiErr); err != nil {
+		return err
+	}
+	if apiErr.Category == CategoryUnknown {
+		apiErr.Detail = firstNonEmpty(apiErr.Detail, fmt.Sprintf("expected status %v", expected))
+	}
+	return apiErr
+}
+
+// CategoryOf returns the category of an error returned by CheckResponse.
+func CategoryOf(err error) Category {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Category
+	}
+	return CategoryUnknown
+}
+
+// Report logs an error diagnostic for every field error of err, so the
+// failing properties show up next to the resource in the Pulumi output.
+func Report(ctx context.Context, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	logger := p.GetLogger(ctx)
+	for _, field := range apiErr.Fields {
+		if field.Path == "" {
+			logger.Errorf("%s: %s", apiErr.Category, field.Message)
+			continue
+		}
+		logger.Errorf("%s: property %q: %s", apiErr.Category, field.Path, field.Message)
+	}
+}
+
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		want       Category
+		wantDetail string
+		wantFields []FieldError
+	}{
+		{
+			name:   "problem details",
+			status: http.StatusUnprocessableEntity,
+			body: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"the request body does not match the spec",
+				"sources":[{"pointer":"/spec/sizeGB","detail":"number must be at least 1"},{"parameter":"limit","detail":"too large"}]}`,
+			want:       CategoryValidation,
+			wantDetail: "the request body does not match the spec",
+			wantFields: []FieldError{{Path: "spec.sizeGB", Message: "number must be at least 1"}, {Path: "limit", Message: "too large"}},
+		},
+		{
+			name:       "errors with fields",
+			status:     http.StatusBadRequest,
+			body:       `{"message":"invalid","errors":[{"field":"spec.zone","message":"unknown zone"},{"path":"/spec/tags/0"}]}`,
+			want:       CategoryValidation,
+			wantDetail: "invalid",
+			wantFields: []FieldError{{Path: "spec.zone", Message: "unknown zone"}, {Path: "spec.tags[0]", Message: "invalid"}},
+		},
+		{
+			name:       "mockserver error",
+			status:     http.StatusConflict,
+			body:       `{"error":"image ubuntu-24.04-amd64 is read-only"}`,
+			want:       CategoryConflict,
+			wantDetail: "image ubuntu-24.04-amd64 is read-only",
+		},
+		{
+			name:   "quota problem type",
+			status: http.StatusForbidden,
+			body:   `{"type":"https://secapi.eu/problems/quota-exceeded","title":"Quota exceeded"}`,
+			want:   CategoryQuota,
+		},
+		{name: "plain text", status: http.StatusBadGateway, body: "bad gateway\n", want: CategoryTransient},
+		{name: "not found", status: http.StatusNotFound, want: CategoryNotFound},
+		{name: "gone", status: http.StatusGone, want: CategoryNotFound},
+		{name: "precondition failed", status: http.StatusPreconditionFailed, want: CategoryConflict},
+		{name: "unauthorized", status: http.StatusUnauthorized, want: CategoryAuth},
+		{name: "too many requests", status: http.StatusTooManyRequests, want: CategoryTransient},
+		{name: "timeout", status: http.StatusRequestTimeout, want: CategoryTransient},
+		{name: "teapot", status: http.StatusTeapot, want: CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := NewAPIError(tt.status, []byte(tt.body))
+			if apiErr.Category != tt.want {
+				t.Errorf("Category = %q, want %q", apiErr.Category, tt.want)
+			}
+			if apiErr.Detail != tt.wantDetail {
+				t.Errorf("Detail = %q, want %q", apiErr.Detail, tt.wantDetail)
+			}
+			if !slices.Equal(apiErr.Fields, tt.wantFields) {
+				t.Errorf("Fields = %+v, want %+v", apiErr.Fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "title and detail", body: `{"title":"Unprocessable Entity","detail":"bad body","sources":[{"pointer":"/spec/sizeGB","detail":"too small"}]}`, want: "validation error (422): Unprocessable Entity: bad body; spec.sizeGB: too small"},
+		{name: "same title and detail", body: `{"title":"bad body","detail":"bad body"}`, want: "validation error (422): bad body"},
+		{name: "title only", body: `{"title":"Unprocessable Entity"}`, want: "validation error (422): Unprocessable Entity"},
+		{name: "raw body", body: "nope", want: "validation error (422): nope"},
+		{name: "empty body", want: "validation error (422)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewAPIError(http.StatusUnprocessableEntity, []byte(tt.body)).Error(); got != tt.want {
+				t.Fatalf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropertyPath(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{pointer: "", want: ""},
+		{pointer: "/", want: ""},
+		{pointer: "sizeGB", want: "sizeGB"},
+		{pointer: "/spec", want: "spec"},
+		{pointer: "/spec/sizeGB", want: "spec.sizeGB"},
+		{pointer: "#/spec/sizeGB", want: "spec.sizeGB"},
+		{pointer: "/spec/networkInterfaces/0/subnetRef", want: "spec.networkInterfaces[0].subnetRef"},
+		{pointer: "/spec/tags/1", want: "spec.tags[1]"},
+		{pointer: "/spec/dataVolumes/0/1", want: "spec.dataVolumes[0][1]"},
+		{pointer: "/labels/a~1b", want: "labels.a/b"},
+		{pointer: "/labels/a~0b", want: "labels.a~b"},
+		{pointer: "/labels/~01", want: "labels.~1"},
+		{pointer: "spec.sizeGB", want: "spec.sizeGB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			if got := PropertyPath(tt.pointer); got != tt.want {
+				t.Fatalf("PropertyPath(%q) = %q, want %q", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	fields := `{"title":"Forbidden","detail":"not allowed","sources":[{"pointer":"/spec/skuRef","detail":"sku not allowed"}]}`
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantIs     error
+		wantAs     any
+		want       Category
+		wantFields int
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"error":"token expired"}`, wantIs: ErrUnauthorized, wantAs: new(*AuthError), want: CategoryAuth},
+		{name: "forbidden", status: http.StatusForbidden, body: fields, wantIs: ErrForbidden, wantAs: new(*AuthError), want: CategoryAuth, wantFields: 1},
+		{name: "version conflict", status: http.StatusPreconditionFailed, body: `{"error":"resource version mismatch"}`, wantIs: ErrConflict, wantAs: new(*ConflictError), want: CategoryConflict},
+		{name: "other conflict", status: http.StatusConflict, body: `{"error":"still referenced","sources":[{"pointer":"/metadata/name"}]}`, wantIs: ErrConflict, wantAs: new(*APIError), want: CategoryConflict, wantFields: 1},
+		{name: "validation", status: http.StatusUnprocessableEntity, body: fields, wantIs: ErrValidation, wantAs: new(*APIError), want: CategoryValidation, wantFields: 1},
+		{name: "unexpected status", status: http.StatusTeapot, wantAs: new(*APIError), want: CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckResponse(tt.status, []byte(tt.body), http.StatusOK)
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("CheckResponse() = %v, want %v", err, tt.wantIs)
+			}
+			if !errors.As(err, tt.wantAs) {
+				t.Errorf("CheckResponse() = %T, want %T", err, tt.wantAs)
+			}
+			if got := CategoryOf(err); got != tt.want {
+				t.Errorf("CategoryOf() = %q, want %q", got, tt.want)
+			}
+			// Report needs the wrapped APIError for every failure.
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("CheckResponse() = %v does not wrap an *APIError", err)
+			}
+			if len(apiErr.Fields) != tt.wantFields {
+				t.Errorf("Fields = %+v, want %d", apiErr.Fields, tt.wantFields)
+			}
+		})
+	}
+	if err := CheckResponse(http.StatusNoContent, nil, http.StatusOK, http.StatusNoContent); err != nil {
+		t.Errorf("CheckResponse(204) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,185 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+const (
+	DefaultMaxRetries           = 5
+	DefaultRetryInitialInterval = 250 * time.Millisecond
+	DefaultRetryMaxInterval     = 10 * time.Second
+)
+
+// sharedTransport is reused by all generated clients so they share one
+// connection pool.
+var sharedTransport http.RoundTripper = http.DefaultTransport
+
+type RetryOptions struct {
+	MaxRetries      int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+func NewRetryOptions(maxRetries *int, initialInterval, maxInterval *string) (RetryOptions, error) {
+	opts := RetryOptions{
+		MaxRetries:      DefaultMaxRetries,
+		InitialInterval: DefaultRetryInitialInterval,
+		MaxInterval:     DefaultRetryMaxInterval,
+	}
+
+	if maxRetries != nil {
+		if *maxRetries < 0 {
+			return RetryOptions{}, fmt.Errorf("maxRetries must not be negative, got %d", *maxRetries)
+		}
+		opts.MaxRetries = *maxRetries
+	}
+	var err error
+	if opts.InitialInterval, err = parseDuration("retryInitialInterval", initialInterval, opts.InitialInterval); err != nil {
+		return RetryOptions{}, err
+	}
+	if opts.MaxInterval, err = parseDuration("retryMaxInterval", maxInterval, opts.MaxInterval); err != nil {
+		return RetryOptions{}, err
+	}
+	if opts.InitialInterval <= 0 {
+		return RetryOptions{}, fmt.Errorf("retryInitialInterval must be positive, got %s", opts.InitialInterval)
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+
+	return opts, nil
+}
+
+// NewHTTPClient returns a client for the generated API clients that retries
+// transient failures of idempotent requests.
+func NewHTTPClient(opts RetryOptions) *http.Client {
+	return &http.Client{
+		Transport: &RetryTransport{Base: sharedTransport, Options: opts},
+	}
+}
+
+// RetryTransport retries idempotent requests on network errors, 429 and 5xx
+// responses with jittered exponential backoff. A Retry-After header on the
+// response takes precedence over the computed backoff, up to MaxInterval.
+// Conditional requests are only retried on 429: after a network error or a
+// 5xx the first attempt may have been applied, and a retry would then fail
+// its precondition against the version that attempt produced.
+type RetryTransport struct {
+	Base    http.RoundTripper
+	Options RetryOptions
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = sharedTransport
+	}
+	if !isIdempotent(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return base.RoundTrip(req)
+	}
+
+	interval := t.Options.InitialInterval
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		res, err := base.RoundTrip(req)
+		if attempt >= t.Options.MaxRetries || !shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		delay := jitter(interval)
+		if retryAfter, ok := parseRetryAfter(res); ok {
+			delay = min(retryAfter, t.Options.MaxInterval)
+		}
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+		}
+		p.GetLogger(req.Context()).Debugf("retrying %s %s in %s (attempt %d of %d): %s", req.Method, req.URL.Path, delay, attempt+1, t.Options.MaxRetries, reason)
+		if res != nil {
+			// drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, req.Context().Err())
+		case <-timer.C:
+		}
+		interval = min(interval*2, t.Options.MaxInterval)
+	}
+}
+
+// SecAPI defines GET, PUT and DELETE as idempotent.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !isConditional(req)
+	}
+	if isConditional(req) {
+		return res.StatusCode == http.StatusTooManyRequests
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func isConditional(req *http.Request) bool {
+	return req.Header.Get("If-Match") != "" || req.Header.Get("If-None-Match") != ""
+}
+
+// jitter returns a random duration in [d/2, d].
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+func parseRetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
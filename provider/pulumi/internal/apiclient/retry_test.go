@@ -0,0 +1,158 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func response(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: header, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+var fastRetry = RetryOptions{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+
+func TestRetryTransport(t *testing.T) {
+	errNetwork := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		method    string
+		header    http.Header
+		responses []int // 0 is a network error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", method: http.MethodGet, responses: []int{200}, wantCalls: 1},
+		{name: "retry 503", method: http.MethodGet, responses: []int{503, 503, 200}, wantCalls: 3},
+		{name: "retry network error", method: http.MethodPut, responses: []int{0, 200}, wantCalls: 2},
+		{name: "give up after max retries", method: http.MethodGet, responses: []int{500, 500, 500, 500, 500}, wantCalls: 4},
+		{name: "no retry of client errors", method: http.MethodGet, responses: []int{404}, wantCalls: 1},
+		{name: "no retry of POST", method: http.MethodPost, responses: []int{503, 200}, wantCalls: 1},
+		{name: "conditional retried on 429", method: http.MethodPut, header: http.Header{"If-Match": {`"3"`}}, responses: []int{429, 200}, wantCalls: 2},
+		{name: "conditional not retried on 5xx", method: http.MethodPut, header: http.Header{"If-Match": {`"3"`}}, responses: []int{502, 200}, wantCalls: 1},
+		{name: "conditional not retried on network error", method: http.MethodPut, header: http.Header{"If-None-Match": {"*"}}, responses: []int{0, 200}, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			transport := &RetryTransport{
+				Options: fastRetry,
+				Base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+					status := tt.responses[calls]
+					calls++
+					if status == 0 {
+						return nil, errNetwork
+					}
+					return response(status, nil), nil
+				}),
+			}
+			req, _ := http.NewRequest(tt.method, "http://localhost/", nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			res, err := transport.RoundTrip(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				_ = res.Body.Close()
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryTransportResendsBody(t *testing.T) {
+	var bodies []string
+	transport := &RetryTransport{
+		Options: fastRetry,
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) == 1 {
+				return response(http.StatusServiceUnavailable, nil), nil
+			}
+			return response(http.StatusOK, nil), nil
+		}),
+	}
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost/", strings.NewReader(`{"spec":{}}`))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"spec":{}}` {
+		t.Fatalf("bodies = %q, want the body sent twice", bodies)
+	}
+}
+
+func TestRetryTransportCapsRetryAfter(t *testing.T) {
+	calls := 0
+	transport := &RetryTransport{
+		Options: fastRetry,
+		Base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return response(http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}}), nil
+			}
+			return response(http.StatusOK, nil), nil
+		}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() = %v, want Retry-After capped at MaxInterval", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "2", want: 2 * time.Second, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(response(http.StatusTooManyRequests, http.Header{"Retry-After": {tt.value}}))
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNewRetryOptions(t *testing.T) {
+	n := func(i int) *int { return &i }
+	s := func(s string) *string { return &s }
+
+	if _, err := NewRetryOptions(n(-1), nil, nil); err == nil {
+		t.Error("NewRetryOptions(maxRetries=-1) succeeded, want error")
+	}
+	if _, err := NewRetryOptions(nil, s("0s"), nil); err == nil {
+		t.Error("NewRetryOptions(initial=0s) succeeded, want error")
+	}
+	got, err := NewRetryOptions(n(0), s("2s"), s("1s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (RetryOptions{MaxRetries: 0, InitialInterval: 2 * time.Second, MaxInterval: 2 * time.Second}); got != want {
+		t.Errorf("NewRetryOptions() = %+v, want %+v", got, want)
+	}
+}
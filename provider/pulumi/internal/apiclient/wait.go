@@ -0,0 +1,126 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWaitTimeout         = 20 * time.Minute
+	DefaultWaitInitialInterval = 500 * time.Millisecond
+	DefaultWaitMaxInterval     = 15 * time.Second
+	waitBackoffMultiplier      = 2
+)
+
+var ErrWaitTimeout = errors.New("timed out waiting for resource")
+
+type WaitOptions struct {
+	// Timeout only applies when the context carries no deadline of its own,
+	// e.g. from Pulumi customTimeouts.
+	Timeout         time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+func NewWaitOptions(timeout, initialInterval, maxInterval *string) (WaitOptions, error) {
+	opts := WaitOptions{
+		Timeout:         DefaultWaitTimeout,
+		InitialInterval: DefaultWaitInitialInterval,
+		MaxInterval:     DefaultWaitMaxInterval,
+	}
+
+	var err error
+	if opts.Timeout, err = parseDuration("waitTimeout", timeout, opts.Timeout); err != nil {
+		return WaitOptions{}, err
+	}
+	if opts.InitialInterval, err = parseDuration("waitInitialInterval", initialInterval, opts.InitialInterval); err != nil {
+		return WaitOptions{}, err
+	}
+	if opts.MaxInterval, err = parseDuration("waitMaxInterval", maxInterval, opts.MaxInterval); err != nil {
+		return WaitOptions{}, err
+	}
+	if opts.InitialInterval <= 0 {
+		return WaitOptions{}, fmt.Errorf("waitInitialInterval must be positive, got %s", opts.InitialInterval)
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+
+	return opts, nil
+}
+
+// Poll calls check until it reports done, returns an error or the wait times out.
+// A canceled context ends the wait with its error instead of ErrWaitTimeout.
+// The interval between calls grows exponentially up to MaxInterval.
+func Poll(ctx context.Context, opts WaitOptions, check func(ctx context.Context) (bool, error)) error {
+	if _, ok := ctx.Deadline(); !ok && opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	interval := opts.InitialInterval
+	if interval <= 0 {
+		interval = DefaultWaitInitialInterval
+	}
+	for {
+		done, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w after %s: %w", ErrWaitTimeout, time.Since(start).Round(time.Second), ctx.Err())
+		case <-timer.C:
+		}
+
+		interval *= waitBackoffMultiplier
+		if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+type StateError struct {
+	Kind    string
+	Name    string
+	State   string
+	Reason  string
+	Message string
+}
+
+func (e *StateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s entered state %q", e.Kind, e.Name, e.State)
+	if e.Reason != "" {
+		fmt.Fprintf(&b, " (reason: %s)", e.Reason)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+func parseDuration(name string, value *string, fallback time.Duration) (time.Duration, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(*value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, *value, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,107 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var fastWait = WaitOptions{Timeout: time.Second, InitialInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+
+func TestPoll(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		opts    WaitOptions
+		check   func(calls int) (bool, error)
+		wantErr error
+	}{
+		{
+			name:  "done after some calls",
+			opts:  fastWait,
+			check: func(calls int) (bool, error) { return calls == 3, nil },
+		},
+		{
+			name:    "check error",
+			opts:    fastWait,
+			check:   func(int) (bool, error) { return false, errFailed },
+			wantErr: errFailed,
+		},
+		{
+			name:    "timeout",
+			opts:    WaitOptions{Timeout: 20 * time.Millisecond, InitialInterval: time.Millisecond},
+			check:   func(int) (bool, error) { return false, nil },
+			wantErr: ErrWaitTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Poll(context.Background(), tt.opts, func(context.Context) (bool, error) {
+				calls++
+				return tt.check(calls)
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Poll() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPollCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Poll(ctx, fastWait, func(context.Context) (bool, error) {
+		cancel()
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("Poll() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPollContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// The context deadline takes precedence over the longer Timeout.
+	opts := WaitOptions{Timeout: time.Hour, InitialInterval: time.Millisecond}
+	err := Poll(ctx, opts, func(context.Context) (bool, error) { return false, nil })
+	if !errors.Is(err, ErrWaitTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Poll() = %v, want %v", err, ErrWaitTimeout)
+	}
+}
+
+func TestNewWaitOptions(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name                  string
+		timeout, initial, max *string
+		want                  WaitOptions
+		wantErr               bool
+	}{
+		{
+			name: "defaults",
+			want: WaitOptions{Timeout: DefaultWaitTimeout, InitialInterval: DefaultWaitInitialInterval, MaxInterval: DefaultWaitMaxInterval},
+		},
+		{
+			name:    "max below initial",
+			timeout: ptr("1m"), initial: ptr("2s"), max: ptr("1s"),
+			want: WaitOptions{Timeout: time.Minute, InitialInterval: 2 * time.Second, MaxInterval: 2 * time.Second},
+		},
+		{name: "invalid duration", timeout: ptr("soon"), wantErr: true},
+		{name: "zero initial interval", initial: ptr("0s"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewWaitOptions(tt.timeout, tt.initial, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewWaitOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("NewWaitOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
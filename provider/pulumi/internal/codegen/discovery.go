@@ -0,0 +1,454 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+type SpecDocument struct {
+	Path       string
+	APIPackage string
+	Package    string
+	Model      *libopenapi.DocumentModel[v3high.Document]
+}
+
+// LoadSpecDocuments reads the API specs (e.g. foundation.compute.v1.yaml) in
+// specRoot. Schema-only files in subdirectories are skipped.
+func LoadSpecDocuments(specRoot string) ([]SpecDocument, error) {
+	entries, err := os.ReadDir(specRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]SpecDocument, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		parts := strings.Split(strings.TrimSuffix(name, ext), ".")
+		if len(parts) < 2 {
+			continue
+		}
+		path := filepath.Join(specRoot, name)
+		model, err := BuildV3Model(path)
+		if err != nil {
+			fmt.Printf("error building v3Model for %s: %v\n", path, err)
+			continue
+		}
+		docs = append(docs, SpecDocument{
+			Path:       path,
+			APIPackage: strings.Join(parts, "/"),
+			Package:    parts[len(parts)-2],
+			Model:      model,
+		})
+	}
+	return docs, nil
+}
+
+// DiscoverResources finds every path that supports PUT, GET and DELETE and
+// turns it into a resource spec, named after the CreateOrUpdate operation.
+func DiscoverResources(docs []SpecDocument) map[string]ControlResourceSpec {
+	resources := map[string]ControlResourceSpec{}
+	for _, doc := range docs {
+		if doc.Model.Model.Paths == nil || doc.Model.Model.Paths.PathItems == nil {
+			continue
+		}
+		for path, item := range doc.Model.Model.Paths.PathItems.FromOldest() {
+			if item == nil || item.Put == nil || item.Get == nil || item.Delete == nil {
+				continue
+			}
+
+			bodySchema := requestBodySchema(item.Put)
+			name := strings.TrimPrefix(item.Put.OperationId, "CreateOrUpdate")
+			if name == "" || name == item.Put.OperationId {
+				if bodySchema == nil || !bodySchema.IsReference() {
+					fmt.Printf("skipping %s %s: cannot derive resource name\n", doc.APIPackage, path)
+					continue
+				}
+				name = RefToSchemaName(bodySchema.GetReference())
+			}
+			if existing, ok := resources[name]; ok {
+				fmt.Printf("skipping %s from %s: already discovered in %s\n", name, doc.APIPackage, existing.APIPackage)
+				continue
+			}
+
+			inputs, outputs := resourceFields(bodySchema)
+			resources[name] = ControlResourceSpec{
+				Package:          doc.Package,
+				APIPackage:       doc.APIPackage,
+				Collection:       collectionFromPath(path),
+				WithoutWorkspace: !strings.Contains(path, "{workspace}"),
+				Input:            inputs,
+				Output:           outputs,
+			}
+		}
+	}
+	return resources
+}
+
+// applyResourceOverrides applies the overrides from the control file on top of
+// the discovered resources. Overrides for resources that are not in the specs
+// are used as they are, `exclude: true` drops a resource.
+func applyResourceOverrides(resources, overrides map[string]ControlResourceSpec) {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		override := overrides[name]
+		discovered, ok := resources[name]
+		if !ok {
+			if override.Package == "" || override.APIPackage == "" {
+				fmt.Printf("skipping override for %s: not found in specs and no package/apiPackage given\n", name)
+				continue
+			}
+			discovered = override
+		} else {
+			discovered = mergeResourceSpec(discovered, override)
+		}
+
+		if override.Exclude {
+			delete(resources, name)
+			continue
+		}
+		resources[name] = discovered
+	}
+}
+
+func mergeResourceSpec(base, override ControlResourceSpec) ControlResourceSpec {
+	if override.Package != "" {
+		base.Package = override.Package
+	}
+	if override.APIPackage != "" {
+		base.APIPackage = override.APIPackage
+	}
+	if override.Collection != "" {
+		base.Collection = override.Collection
+	}
+	if override.WithoutWorkspace {
+		base.WithoutWorkspace = true
+	}
+	if override.WithCustomGenerators {
+		base.WithCustomGenerators = true
+	}
+	if len(override.Input) > 0 {
+		base.Input = override.Input
+	}
+	if len(override.Output) > 0 {
+		base.Output = override.Output
+	}
+	if len(override.ReplaceOnChange) > 0 {
+		base.ReplaceOnChange = override.ReplaceOnChange
+	}
+	return base
+}
+
+func requestBodySchema(op *v3high.Operation) *base.SchemaProxy {
+	if op == nil || op.RequestBody == nil || op.RequestBody.Content == nil {
+		return nil
+	}
+	if media, ok := op.RequestBody.Content.Get("application/json"); ok && media != nil {
+		return media.Schema
+	}
+	for _, media := range op.RequestBody.Content.FromOldest() {
+		if media != nil && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+var outputProperties = map[string]bool{
+	"metadata": true,
+	"status":   true,
+}
+
+func resourceFields(schemaProxy *base.SchemaProxy) ([]InOutSpec, []InOutSpec) {
+	inputs := make([]InOutSpec, 0)
+	outputs := make([]InOutSpec, 0)
+	if schemaProxy == nil {
+		return inputs, outputs
+	}
+
+	seen := map[string]bool{}
+	collect := func(schema *base.Schema) {
+		if schema == nil || schema.Properties == nil {
+			return
+		}
+		for propName := range schema.Properties.KeysFromOldest() {
+			if seen[propName] {
+				continue
+			}
+			seen[propName] = true
+			field := InOutSpec{Name: PascalCase(propName)}
+			if outputProperties[propName] {
+				outputs = append(outputs, field)
+			} else {
+				inputs = append(inputs, field)
+			}
+		}
+	}
+
+	schema := schemaProxy.Schema()
+	collect(schema)
+	if schema != nil {
+		for _, allOf := range schema.AllOf {
+			if allOf != nil {
+				collect(allOf.Schema())
+			}
+		}
+	}
+	return inputs, outputs
+}
+
+func collectionFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(segments[i], "{") {
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+// DiscoverGetterFunctions turns every List* and Get* operation into a getter
+// function. List operations are renamed to Get*, items that are resources of
+// the same package are returned as their resource state.
+func DiscoverGetterFunctions(docs []SpecDocument, resources map[string]ControlResourceSpec) map[string]map[string]ProviderGetterFunction {
+	functions := map[string]map[string]ProviderGetterFunction{}
+	for _, doc := range docs {
+		if doc.Model.Model.Paths == nil || doc.Model.Model.Paths.PathItems == nil {
+			continue
+		}
+		for path, item := range doc.Model.Model.Paths.PathItems.FromOldest() {
+			if item == nil || item.Get == nil {
+				continue
+			}
+			op := item.Get
+			list := strings.HasPrefix(op.OperationId, "List")
+			if !list && !strings.HasPrefix(op.OperationId, "Get") {
+				continue
+			}
+
+			responseSchema := responseBodySchema(op)
+			if responseSchema == nil || !responseSchema.IsReference() {
+				fmt.Printf("skipping %s %s: no 200 response schema\n", doc.APIPackage, op.OperationId)
+				continue
+			}
+
+			fun := ProviderGetterFunction{
+				APIPackage:       doc.APIPackage,
+				WithoutWorkspace: !strings.Contains(path, "{workspace}"),
+				ClientFunction:   op.OperationId,
+				List:             list,
+				Description:      NormalizeDescription(firstNonEmpty(op.Summary, op.Description)),
+			}
+
+			itemSchema := responseSchema
+			if list {
+				fun.ResponseType = qualifiedSchemaType(responseSchema)
+				itemSchema = listItemSchema(responseSchema)
+				if itemSchema == nil || !itemSchema.IsReference() {
+					fmt.Printf("skipping %s %s: cannot resolve list item type\n", doc.APIPackage, op.OperationId)
+					continue
+				}
+			}
+			itemName := RefToSchemaName(itemSchema.GetReference())
+			fun.OutputType = itemName
+			fun.ItemAPIType = qualifiedSchemaType(itemSchema)
+			if resource, ok := resources[itemName]; ok && resource.Package == doc.Package {
+				fun.ItemResource = itemName
+			}
+			if !list {
+				fun.ResponseType = fun.ItemAPIType
+			}
+
+			fun.PathParams, fun.QueryParams, fun.HeaderParams = operationParams(path, item, op)
+
+			name := op.OperationId
+			if list {
+				name = "Get" + strings.TrimPrefix(name, "List")
+			}
+			if functions[doc.Package] == nil {
+				functions[doc.Package] = map[string]ProviderGetterFunction{}
+			}
+			if _, ok := functions[doc.Package][name]; ok {
+				fmt.Printf("skipping %s from %s: function name already taken\n", name, doc.APIPackage)
+				continue
+			}
+			functions[doc.Package][name] = fun
+		}
+	}
+	return functions
+}
+
+// applyGetterOverrides applies the overrides from the control file on top of
+// the discovered getter functions. Overrides can rename the client function or
+// output type or drop a function with `exclude: true`.
+func applyGetterOverrides(functions map[string]map[string]ProviderGetterFunction, overrides map[string]map[string]ProviderGetterFunction) {
+	for packageName, overrides := range overrides {
+		for name, override := range overrides {
+			discovered, ok := functions[packageName][name]
+			if !ok {
+				fmt.Printf("skipping override for %s.%s: not found in specs\n", packageName, name)
+				continue
+			}
+			if override.Exclude {
+				delete(functions[packageName], name)
+				continue
+			}
+			if override.ClientFunction != "" {
+				discovered.ClientFunction = override.ClientFunction
+			}
+			if override.OutputType != "" {
+				discovered.OutputType = override.OutputType
+			}
+			if override.ResponseType != "" {
+				discovered.ResponseType = override.ResponseType
+			}
+			functions[packageName][name] = discovered
+		}
+	}
+}
+
+func responseBodySchema(op *v3high.Operation) *base.SchemaProxy {
+	if op == nil || op.Responses == nil || op.Responses.Codes == nil {
+		return nil
+	}
+	response, ok := op.Responses.Codes.Get("200")
+	if !ok || response == nil || response.Content == nil {
+		return nil
+	}
+	if media, ok := response.Content.Get("application/json"); ok && media != nil {
+		return media.Schema
+	}
+	for _, media := range response.Content.FromOldest() {
+		if media != nil && media.Schema != nil {
+			return media.Schema
+		}
+	}
+	return nil
+}
+
+func listItemSchema(iterator *base.SchemaProxy) *base.SchemaProxy {
+	schema := iterator.Schema()
+	if schema == nil || schema.Properties == nil {
+		return nil
+	}
+	items, ok := schema.Properties.Get("items")
+	if !ok || items == nil {
+		return nil
+	}
+	itemsSchema := items.Schema()
+	if itemsSchema == nil || itemsSchema.Items == nil || !itemsSchema.Items.IsA() {
+		return nil
+	}
+	return itemsSchema.Items.A
+}
+
+// qualifiedSchemaType returns the Go type for a referenced schema. Schemas in
+// the shared schema files end up in the models package, schemas local to an
+// API spec in the API package itself.
+func qualifiedSchemaType(schema *base.SchemaProxy) string {
+	ref := schema.GetReference()
+	name := RefToSchemaName(ref)
+	if strings.HasPrefix(ref, "#/") {
+		return "api." + name
+	}
+	return "models." + name
+}
+
+func operationParams(path string, item *v3high.PathItem, op *v3high.Operation) ([]GetterParam, []GetterParam, []string) {
+	params := make([]*v3high.Parameter, 0, len(item.Parameters)+len(op.Parameters))
+	params = append(params, item.Parameters...)
+	params = append(params, op.Parameters...)
+
+	pathParams := make([]GetterParam, 0)
+	queryParams := make([]GetterParam, 0)
+	headerParams := make([]string, 0)
+	seen := map[string]bool{}
+	for _, param := range params {
+		if param == nil || seen[param.In+"/"+param.Name] {
+			continue
+		}
+		seen[param.In+"/"+param.Name] = true
+
+		getterParam := GetterParam{
+			Name:        param.Name,
+			GoName:      PascalCase(param.Name),
+			GoType:      paramGoType(param),
+			Required:    param.Required != nil && *param.Required,
+			Description: NormalizeDescription(param.Description),
+		}
+		switch param.In {
+		case "path":
+			pathParams = append(pathParams, getterParam)
+		case "query":
+			queryParams = append(queryParams, getterParam)
+		case "header":
+			headerParams = append(headerParams, getterParam.GoName)
+		}
+	}
+
+	// the generated client takes path parameters in the order of the path
+	sort.SliceStable(pathParams, func(i, j int) bool {
+		return strings.Index(path, "{"+pathParams[i].Name+"}") < strings.Index(path, "{"+pathParams[j].Name+"}")
+	})
+	return pathParams, queryParams, headerParams
+}
+
+func paramGoType(param *v3high.Parameter) string {
+	if param.Schema == nil {
+		return "string"
+	}
+	return EnumBaseType(param.Schema.Schema())
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Discovery holds the resources and getter functions discovered from the
+// specs, with the overrides of the control file applied.
+type Discovery struct {
+	Resources       map[string]ControlResourceSpec
+	GetterFunctions map[string]map[string]ProviderGetterFunction
+}
+
+// Discover parses the specs in specRoot and discovers the resources and
+// getter functions with the overrides of the control file applied.
+func Discover(controlPath, specRoot string) (Discovery, error) {
+	genYaml, err := GetPulumiGenYaml(controlPath)
+	if err != nil {
+		return Discovery{}, err
+	}
+	docs, err := LoadSpecDocuments(specRoot)
+	if err != nil {
+		return Discovery{}, err
+	}
+	resources := DiscoverResources(docs)
+	applyResourceOverrides(resources, genYaml.Resources)
+	functions := DiscoverGetterFunctions(docs, resources)
+	applyGetterOverrides(functions, genYaml.GetterFunctions)
+	return Discovery{Resources: resources, GetterFunctions: functions}, nil
+}
@@ -69,10 +69,13 @@ type PulumiGenYaml struct {
 type ControlResourceSpec struct {
 	Package              string      `yaml:"package"`
 	APIPackage           string      `yaml:"apiPackage"`
+	Collection           string      `yaml:"collection"`
 	WithoutWorkspace     bool        `yaml:"withoutWorkspace"`
 	WithCustomGenerators bool        `yaml:"withCustomGenerators"`
 	Input                []InOutSpec `yaml:"input"`
 	Output               []InOutSpec `yaml:"output"`
+	ReplaceOnChange      []string    `yaml:"replaceOnChange"`
+	Exclude              bool        `yaml:"exclude"`
 }
 
 type ProviderGetterFunction struct {
@@ -81,6 +84,23 @@ type ProviderGetterFunction struct {
 	ClientFunction   string `yaml:"clientFunction"`
 	OutputType       string `yaml:"outputType"`
 	ResponseType     string `yaml:"responseType"`
+	Exclude          bool   `yaml:"exclude"`
+
+	List         bool          `yaml:"-"`
+	Description  string        `yaml:"-"`
+	PathParams   []GetterParam `yaml:"-"`
+	QueryParams  []GetterParam `yaml:"-"`
+	HeaderParams []string      `yaml:"-"`
+	ItemAPIType  string        `yaml:"-"`
+	ItemResource string        `yaml:"-"`
+}
+
+type GetterParam struct {
+	Name        string
+	GoName      string
+	GoType      string
+	Required    bool
+	Description string
 }
 
 type InOutSpec struct {
@@ -140,21 +160,6 @@ func GetPulumiGenYaml(path string) (PulumiGenYaml, error) {
 	return doc, nil
 }
 
-func LoadControlResources(path string) (map[string]ControlResourceSpec, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	var doc PulumiGenYaml
-	if err := yaml.Unmarshal(data, &doc); err != nil {
-		return nil, err
-	}
-	if doc.Resources == nil {
-		doc.Resources = map[string]ControlResourceSpec{}
-	}
-	return doc.Resources, nil
-}
-
 type ModelEntry struct {
 	Path  string
 	Model *libopenapi.DocumentModel[v3high.Document]
@@ -234,6 +239,23 @@ func LowerCamel(name string) string {
 	return string(runes)
 }
 
+func KebabCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('-')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 func PascalCase(s string) string {
 	if s == "" {
 		return ""
@@ -334,3 +356,18 @@ func DefaultValueLiteral(schemaProxy *base.SchemaProxy) (string, bool) {
 	baseType := EnumBaseType(schema)
 	return EnumValueLiteral(baseType, schema.Default), true
 }
+
+func ExtensionBool(schema *base.Schema, key string) bool {
+	if schema == nil || schema.Extensions == nil {
+		return false
+	}
+	var node *yaml.Node
+	schema.Extensions.FromOldest()(func(name string, value *yaml.Node) bool {
+		if name == key {
+			node = value
+			return false
+		}
+		return true
+	})
+	return node != nil && node.Kind == yaml.ScalarNode && node.Value == "true"
+}
@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+// Options name the property paths of a resource that Compute treats
+// specially.
+type Options struct {
+	// ReplaceOnChanges are paths whose changes (or changes below them)
+	// require a replacement.
+	ReplaceOnChanges []string
+	// Identity paths, e.g. tenant and workspace, locate the resource together
+	// with its name. A replacement is only created before the old resource is
+	// deleted if one of them changed.
+	Identity []string
+	// ReadOnly paths are set by the server, changes to them are ignored.
+	ReadOnly []string
+}
+
+// Compute compares old and new resource inputs property by property.
+func Compute(olds, news any, opts Options) p.DiffResponse {
+	oldProps := map[string]any{}
+	newProps := map[string]any{}
+	flatten("", reflect.ValueOf(olds), oldProps)
+	flatten("", reflect.ValueOf(news), newProps)
+
+	paths := make([]string, 0, len(oldProps)+len(newProps))
+	for path := range oldProps {
+		paths = append(paths, path)
+	}
+	for path := range newProps {
+		if _, ok := oldProps[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	detailed := map[string]p.PropertyDiff{}
+	replace, moved := false, false
+	for _, path := range paths {
+		if underAny(path, opts.ReadOnly) {
+			continue
+		}
+		oldValue, hadOld := oldProps[path]
+		newValue, hasNew := newProps[path]
+
+		var kind p.DiffKind
+		switch {
+		case hadOld && hasNew:
+			if reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			kind = p.Update
+		case hasNew:
+			kind = p.Add
+		default:
+			kind = p.Delete
+		}
+
+		if requiresReplace(path, opts.ReplaceOnChanges) {
+			replace = true
+			switch kind {
+			case p.Add:
+				kind = p.AddReplace
+			case p.Delete:
+				kind = p.DeleteReplace
+			default:
+				kind = p.UpdateReplace
+			}
+		}
+		if slices.Contains(opts.Identity, path) {
+			moved = true
+		}
+		detailed[path] = p.PropertyDiff{Kind: kind, InputDiff: true}
+	}
+
+	return p.DiffResponse{
+		// A replacement at the same path collides with the old resource, so
+		// that has to be gone first.
+		DeleteBeforeReplace: replace && !moved,
+		HasChanges:          len(detailed) > 0,
+		DetailedDiff:        detailed,
+	}
+}
+
+func requiresReplace(path string, replaceOnChanges []string) bool {
+	for _, candidate := range replaceOnChanges {
+		if path == candidate || strings.HasPrefix(path, candidate+".") || strings.HasPrefix(candidate, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// underAny reports whether path is one of paths or below one of them.
+func underAny(path string, paths []string) bool {
+	for _, candidate := range paths {
+		if path == candidate || strings.HasPrefix(path, candidate+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func flatten(prefix string, v reflect.Value, out map[string]any) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		if prefix != "" && !isEmpty(v) {
+			out[prefix] = v.Interface()
+		}
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := propertyName(field)
+		if !ok {
+			if field.Anonymous {
+				flatten(prefix, v.Field(i), out)
+			}
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		flatten(path, v.Field(i), out)
+	}
+}
+
+func propertyName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("pulumi")
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" || name == "-" {
+		return "", false
+	}
+	return name, true
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.IsNil() || v.Len() == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"testing"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+type spec struct {
+	SkuRef string `pulumi:"skuRef"`
+	SizeGB int    `pulumi:"sizeGB"`
+	Zone   string `pulumi:"zone,optional"`
+}
+
+type args struct {
+	Tenant    *string           `pulumi:"tenant,optional"`
+	Workspace *string           `pulumi:"workspace,optional"`
+	Labels    map[string]string `pulumi:"labels,optional"`
+	Spec      spec              `pulumi:"spec"`
+}
+
+var opts = Options{
+	ReplaceOnChanges: []string{"tenant", "workspace", "spec.skuRef"},
+	Identity:         []string{"tenant", "workspace"},
+	ReadOnly:         []string{"spec.zone"},
+}
+
+func TestCompute(t *testing.T) {
+	t1, t2 := "t1", "t2"
+	base := args{Tenant: &t1, Spec: spec{SkuRef: "skus/rd100", SizeGB: 10}}
+
+	tests := []struct {
+		name                string
+		change              func(a *args)
+		wantChanges         bool
+		wantDetailed        map[string]p.DiffKind
+		wantDeleteBeforeRep bool
+	}{
+		{
+			name:         "no change",
+			change:       func(*args) {},
+			wantDetailed: map[string]p.DiffKind{},
+		},
+		{
+			name:         "update",
+			change:       func(a *args) { a.Spec.SizeGB = 20 },
+			wantChanges:  true,
+			wantDetailed: map[string]p.DiffKind{"spec.sizeGB": p.Update},
+		},
+		{
+			name:         "added label",
+			change:       func(a *args) { a.Labels = map[string]string{"env": "dev"} },
+			wantChanges:  true,
+			wantDetailed: map[string]p.DiffKind{"labels": p.Add},
+		},
+		{
+			name:                "replace at the same path deletes first",
+			change:              func(a *args) { a.Spec.SkuRef = "skus/rd500" },
+			wantChanges:         true,
+			wantDetailed:        map[string]p.DiffKind{"spec.skuRef": p.UpdateReplace},
+			wantDeleteBeforeRep: true,
+		},
+		{
+			name: "replace in another tenant creates first",
+			change: func(a *args) {
+				a.Tenant = &t2
+				a.Spec.SkuRef = "skus/rd500"
+			},
+			wantChanges:  true,
+			wantDetailed: map[string]p.DiffKind{"tenant": p.UpdateReplace, "spec.skuRef": p.UpdateReplace},
+		},
+		{
+			name:         "read-only change is ignored",
+			change:       func(a *args) { a.Spec.Zone = "b" },
+			wantDetailed: map[string]p.DiffKind{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			news := base
+			tt.change(&news)
+			got := Compute(base, news, opts)
+
+			if got.HasChanges != tt.wantChanges {
+				t.Errorf("HasChanges = %v, want %v", got.HasChanges, tt.wantChanges)
+			}
+			if got.DeleteBeforeReplace != tt.wantDeleteBeforeRep {
+				t.Errorf("DeleteBeforeReplace = %v, want %v", got.DeleteBeforeReplace, tt.wantDeleteBeforeRep)
+			}
+			if len(got.DetailedDiff) != len(tt.wantDetailed) {
+				t.Fatalf("DetailedDiff = %v, want %v", got.DetailedDiff, tt.wantDetailed)
+			}
+			for path, kind := range tt.wantDetailed {
+				if got.DetailedDiff[path].Kind != kind {
+					t.Errorf("DetailedDiff[%s] = %v, want %v", path, got.DetailedDiff[path].Kind, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestRequiresReplace(t *testing.T) {
+	paths := []string{"spec.bootVolume"}
+	tests := map[string]bool{
+		"spec.bootVolume":           true,
+		"spec.bootVolume.deviceRef": true,
+		"spec":                      true,
+		"spec.bootVolumes":          false,
+		"spec.zone":                 false,
+	}
+	for path, want := range tests {
+		if got := requiresReplace(path, paths); got != want {
+			t.Errorf("requiresReplace(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -17,6 +18,7 @@ import (
 )
 
 const SchemasDir = "../../../ext/secapi/spec/schemas"
+const SpecDir = "../../../ext/secapi/spec"
 const PulumiControlResourceFile = "../pulumi.gen.yaml"
 const SchemasImportPath = "cape-project.eu/provider/pulumi/internal/schemas"
 
@@ -25,6 +27,7 @@ var createTemplate = codegen.ReadTemplate("create", "codegen/create.tmpl")
 var readTemplate = codegen.ReadTemplate("read", "codegen/read.tmpl")
 var updateTemplate = codegen.ReadTemplate("update", "codegen/update.tmpl")
 var deleteTemplate = codegen.ReadTemplate("delete", "codegen/delete.tmpl")
+var diffTemplate = codegen.ReadTemplate("diff", "codegen/diff.tmpl")
 var apiTemplate = codegen.ReadTemplate("api", "codegen/api.tmpl")
 var converterTemplate = codegen.ReadTemplate("converter", "codegen/converter.tmpl")
 
@@ -38,14 +41,19 @@ func main() {
 	if !filepath.IsAbs(controlPath) {
 		controlPath = filepath.Join(cwd, controlPath)
 	}
+	specRoot := SpecDir
+	if !filepath.IsAbs(specRoot) {
+		specRoot = filepath.Join(cwd, specRoot)
+	}
 
 	models := codegen.GetModelsForPath(schemaRoot)
 	resolver := codegen.NewSchemaResolver(models)
-	resources, err := codegen.LoadControlResources(controlPath)
+	discovery, err := codegen.Discover(controlPath, specRoot)
 	if err != nil {
 		fmt.Printf("error reading control resources: %v\n", err)
 		return
 	}
+	resources := discovery.Resources
 
 	resourceNames := make([]string, 0, len(resources))
 	for name := range resources {
@@ -85,6 +93,10 @@ func main() {
 		outPath = filepath.Join(outDir, fileName)
 		writeTemplate(outPath, def, deleteTemplate)
 
+		fileName = fmt.Sprintf("%s.diff.gen.go", strings.ToLower(name))
+		outPath = filepath.Join(outDir, fileName)
+		writeTemplate(outPath, def, diffTemplate)
+
 		fileName = fmt.Sprintf("%s.api.gen.go", strings.ToLower(name))
 		outPath = filepath.Join(outDir, fileName)
 		writeTemplate(outPath, def, apiTemplate)
@@ -113,6 +125,7 @@ type resourceDef struct {
 	Package              string
 	APIPackage           string
 	APIPackageID         string
+	Collection           string
 	WithoutWorkspace     bool
 	WithCustomGenerators bool
 	Inputs               []resourceField
@@ -120,6 +133,8 @@ type resourceDef struct {
 	ResourceDesc         string
 	ArgsAnnotateLines    []string
 	StateAnnotateLines   []string
+	ReplaceOnChanges     []string
+	ReadOnlyPaths        []string
 }
 
 func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *codegen.SchemaResolver) resourceDef {
@@ -232,6 +247,7 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 		Package:              spec.Package,
 		APIPackage:           spec.APIPackage,
 		APIPackageID:         s[len(s)-1],
+		Collection:           collectionName(name, spec),
 		WithoutWorkspace:     spec.WithoutWorkspace,
 		WithCustomGenerators: spec.WithCustomGenerators,
 		Inputs:               inputs,
@@ -239,7 +255,101 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 		ResourceDesc:         resourceDesc,
 		ArgsAnnotateLines:    argsAnnotate,
 		StateAnnotateLines:   stateAnnotate,
+		ReplaceOnChanges:     replaceOnChanges(name, spec, resolver),
+		ReadOnlyPaths:        readOnlyPaths(name, spec, resolver),
+	}
+}
+
+func collectionName(name string, spec codegen.ControlResourceSpec) string {
+	if spec.Collection != "" {
+		return spec.Collection
+	}
+	return codegen.KebabCase(name) + "s"
+}
+
+func replaceOnChanges(name string, spec codegen.ControlResourceSpec, resolver *codegen.SchemaResolver) []string {
+	paths := make([]string, 0, len(spec.ReplaceOnChange))
+	for _, path := range spec.ReplaceOnChange {
+		paths = append(paths, strings.TrimSpace(path))
+	}
+	paths = append(paths, inputPaths(name, spec, resolver, func(schema *base.Schema) bool {
+		return codegen.ExtensionBool(schema, "x-immutable")
+	})...)
+	return uniquePaths(paths)
+}
+
+// readOnlyPaths returns the input paths the spec marks readOnly. They are set
+// by the server, so changing them is no change of the resource.
+func readOnlyPaths(name string, spec codegen.ControlResourceSpec, resolver *codegen.SchemaResolver) []string {
+	return uniquePaths(inputPaths(name, spec, resolver, func(schema *base.Schema) bool {
+		return schema.ReadOnly != nil && *schema.ReadOnly
+	}))
+}
+
+func inputPaths(name string, spec codegen.ControlResourceSpec, resolver *codegen.SchemaResolver, marked func(*base.Schema) bool) []string {
+	var paths []string
+	for _, input := range spec.Input {
+		if input.Type != "" {
+			continue
+		}
+		prop := lookupResourceProperty(name, input.Name, resolver)
+		paths = append(paths, markedPaths(codegen.LowerCamel(input.Name), prop, resolver, 0, marked)...)
+	}
+	return paths
+}
+
+func uniquePaths(paths []string) []string {
+	unique := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path != "" && !slices.Contains(unique, path) {
+			unique = append(unique, path)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// markedPaths collects the property paths below the given schema for which
+// marked holds. Nesting is limited to keep recursive schemas finite.
+func markedPaths(path string, schemaProxy *base.SchemaProxy, resolver *codegen.SchemaResolver, depth int, marked func(*base.Schema) bool) []string {
+	schema := resolveSchema(schemaProxy, resolver)
+	if schema == nil || depth > 4 {
+		return nil
+	}
+	if marked(schema) {
+		return []string{path}
+	}
+
+	paths := make([]string, 0)
+	collect := func(s *base.Schema) {
+		if s == nil || s.Properties == nil {
+			return
+		}
+		for propName, propSchema := range s.Properties.FromOldest() {
+			paths = append(paths, markedPaths(path+"."+propName, propSchema, resolver, depth+1, marked)...)
+		}
+	}
+	collect(schema)
+	for _, allOf := range schema.AllOf {
+		inner := resolveSchema(allOf, resolver)
+		if inner != nil && marked(inner) {
+			return []string{path}
+		}
+		collect(inner)
+	}
+	return paths
+}
+
+func resolveSchema(schemaProxy *base.SchemaProxy, resolver *codegen.SchemaResolver) *base.Schema {
+	if schemaProxy == nil {
+		return nil
+	}
+	if schemaProxy.IsReference() && resolver != nil {
+		if ref := resolver.Lookup(codegen.RefToSchemaName(schemaProxy.GetReference())); ref != nil {
+			return ref.Schema()
+		}
 	}
+	return schemaProxy.Schema()
 }
 
 func writeTemplate(outPath string, def resourceDef, tmpl *template.Template) {
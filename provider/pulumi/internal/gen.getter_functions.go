@@ -6,71 +6,170 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"cape-project.eu/provider/pulumi/internal/codegen"
 )
 
-const SchemasDir = "../../../ext/secapi/spec/schemas"
+const SpecDir = "../../../ext/secapi/spec"
 const PulumiControlResourceFile = "../pulumi.gen.yaml"
-const SchemasImportPath = "cape-project.eu/provider/pulumi/internal/schemas"
 
 var getterFunTmpl = codegen.ReadTemplate("getter_functions", "codegen/getter_functions.tmpl")
 
 func main() {
 	cwd, _ := os.Getwd()
-	schemaRoot := SchemasDir
-	if !filepath.IsAbs(schemaRoot) {
-		schemaRoot = filepath.Join(cwd, schemaRoot)
+	specRoot := SpecDir
+	if !filepath.IsAbs(specRoot) {
+		specRoot = filepath.Join(cwd, specRoot)
 	}
 	controlPath := PulumiControlResourceFile
 	if !filepath.IsAbs(controlPath) {
 		controlPath = filepath.Join(cwd, controlPath)
 	}
 
-	models := codegen.GetModelsForPath(schemaRoot)
-	_ = codegen.NewSchemaResolver(models)
-
-	genYaml, err := codegen.GetPulumiGenYaml(controlPath)
+	discovery, err := codegen.Discover(controlPath, specRoot)
 	if err != nil {
-		panic(err)
+		fmt.Printf("error reading getter functions: %v\n", err)
+		os.Exit(1)
+	}
+	functions := discovery.GetterFunctions
+
+	packageNames := make([]string, 0, len(functions))
+	for packageName := range functions {
+		packageNames = append(packageNames, packageName)
 	}
+	sort.Strings(packageNames)
 
-	for packageName, functions := range genYaml.GetterFunctions {
-		for functionName, function := range functions {
-			writeTemplate(fmt.Sprintf("./%s/%s.gen.go", packageName, strings.ToLower(functionName)), tmplData{
-				Package:          packageName,
-				Name:             functionName,
-				APIPackage:       function.APIPackage,
-				WithoutWorkspace: function.WithoutWorkspace,
-				ClientFunction:   function.ClientFunction,
-				OutputType:       function.OutputType,
-				ResponseType:     function.ResponseType,
-			})
+	for _, packageName := range packageNames {
+		for functionName, function := range functions[packageName] {
+			if err := os.MkdirAll(filepath.Join(cwd, packageName), 0o755); err != nil {
+				fmt.Printf("error creating output dir %s: %v\n", packageName, err)
+				continue
+			}
+			writeTemplate(fmt.Sprintf("./%s/%s.gen.go", packageName, strings.ToLower(functionName)), buildTmplData(packageName, functionName, function))
 		}
 	}
 }
 
+type argDef struct {
+	GoName      string
+	GoType      string
+	Tag         string
+	Description string
+}
+
 type tmplData struct {
 	Package          string
 	Name             string
+	Description      string
 	APIPackage       string
 	WithoutWorkspace bool
 	ClientFunction   string
-	OutputType       string
+	List             bool
 	ResponseType     string
+	ItemAPIType      string
+	ItemType         string
+	ItemConverter    string
+	ItemResource     bool
+	Args             []argDef
+	PathArgs         []string
+	HasParams        bool
+	HeaderParams     []string
+	Paginated        bool
+	ImportModels     bool
+	ImportSchemas    bool
+}
+
+func buildTmplData(packageName, name string, function codegen.ProviderGetterFunction) tmplData {
+	data := tmplData{
+		Package:          packageName,
+		Name:             name,
+		APIPackage:       function.APIPackage,
+		WithoutWorkspace: function.WithoutWorkspace,
+		ClientFunction:   function.ClientFunction,
+		List:             function.List,
+		ResponseType:     function.ResponseType,
+		ItemAPIType:      function.ItemAPIType,
+		ItemType:         "schemas." + function.OutputType,
+		ItemConverter:    "convertOpenAPITo" + name + "Item",
+		HasParams:        len(function.QueryParams) > 0 || len(function.HeaderParams) > 0,
+		HeaderParams:     function.HeaderParams,
+	}
+	if function.Description != "" {
+		data.Description = strconv.Quote(function.Description)
+	}
+	if function.ItemResource != "" {
+		data.ItemResource = true
+		data.ItemType = function.ItemResource + "State"
+		data.ItemConverter = "convertOpenAPITo" + function.ItemResource + "State"
+	}
+
+	data.ImportModels = !data.ItemResource && strings.HasPrefix(data.ItemAPIType, "models.")
+	data.ImportSchemas = !data.ItemResource
+
+	for _, param := range function.PathParams {
+		switch param.Name {
+		case "tenant", "workspace":
+			data.PathArgs = append(data.PathArgs, param.Name)
+			continue
+		}
+		data.PathArgs = append(data.PathArgs, "req.Input."+param.GoName)
+		data.Args = append(data.Args, argDef{
+			GoName:      param.GoName,
+			GoType:      "string",
+			Tag:         param.Name,
+			Description: quoteDescription(param.Description),
+		})
+	}
+	for _, param := range function.QueryParams {
+		// list functions follow the continuation token themselves
+		if data.List && param.Name == "skipToken" {
+			data.Paginated = true
+			continue
+		}
+		arg := argDef{
+			GoName:      param.GoName,
+			GoType:      param.GoType,
+			Tag:         param.Name,
+			Description: quoteDescription(param.Description),
+		}
+		if !param.Required {
+			arg.GoType = "*" + arg.GoType
+			arg.Tag += ",optional"
+		}
+		data.Args = append(data.Args, arg)
+	}
+	if data.List {
+		data.Args = append(data.Args, argDef{
+			GoName:      "MaxItems",
+			GoType:      "*int",
+			Tag:         "maxItems,optional",
+			Description: strconv.Quote("The maximum number of items to return. If omitted, all items are returned."),
+		})
+	}
+	return data
+}
+
+func quoteDescription(desc string) string {
+	if desc == "" {
+		return ""
+	}
+	return strconv.Quote(desc)
 }
 
 func writeTemplate(outPath string, data tmplData) {
 	outFile, err := os.Create(outPath)
 	if err != nil {
-		println(fmt.Errorf("error creating/opening file: %s", err))
-		return
+		fmt.Printf("error creating/opening file: %v\n", err)
+		os.Exit(1)
 	}
 	defer func() {
 		_ = outFile.Close()
 	}()
 	if err := getterFunTmpl.Execute(outFile, data); err != nil {
-		println(fmt.Errorf("error executing template: %s", err))
+		fmt.Printf("error executing template: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,75 @@
+package resourceid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ID identifies a SecAPI resource. Its string form is the resource path used by
+// SecAPI itself, e.g. "tenants/t1/workspaces/ws1/block-storages/data", which
+// stays unambiguous because none of the segments may contain a slash.
+type ID struct {
+	Tenant     string
+	Workspace  string
+	Collection string
+	Name       string
+}
+
+func Format(tenant, workspace, collection, name string) string {
+	return ID{Tenant: tenant, Workspace: workspace, Collection: collection, Name: name}.String()
+}
+
+func (id ID) String() string {
+	if id.Workspace == "" {
+		return fmt.Sprintf("tenants/%s/%s/%s", id.Tenant, id.Collection, id.Name)
+	}
+	return fmt.Sprintf("tenants/%s/workspaces/%s/%s/%s", id.Tenant, id.Workspace, id.Collection, id.Name)
+}
+
+func Parse(raw, collection string, withWorkspace bool) (ID, error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(raw), "/"), "/")
+
+	expected := fmt.Sprintf("tenants/<tenant>/%s/<name>", collection)
+	if withWorkspace {
+		expected = fmt.Sprintf("tenants/<tenant>/workspaces/<workspace>/%s/<name>", collection)
+	}
+
+	var id ID
+	switch {
+	case !withWorkspace && len(parts) == 4 && parts[0] == "tenants" && parts[2] == collection:
+		id = ID{Tenant: parts[1], Collection: parts[2], Name: parts[3]}
+	case withWorkspace && len(parts) == 6 && parts[0] == "tenants" && parts[2] == "workspaces" && parts[4] == collection:
+		id = ID{Tenant: parts[1], Workspace: parts[3], Collection: parts[4], Name: parts[5]}
+	default:
+		return ID{}, fmt.Errorf("invalid resource id %q, expected %s", raw, expected)
+	}
+
+	if id.Tenant == "" || id.Name == "" || (withWorkspace && id.Workspace == "") {
+		return ID{}, fmt.Errorf("invalid resource id %q, expected %s", raw, expected)
+	}
+	return id, nil
+}
+
+// Resolve returns the ID of a resource that is read. Imports have no state
+// (stateName is empty) and need a valid raw ID. State written before IDs were
+// resource paths has an ID that cannot be parsed, the resource is then located
+// by the name in the state and the tenant and workspace of fallback.
+func Resolve(raw, collection string, withWorkspace bool, stateName string, fallback ID) (ID, error) {
+	id, err := Parse(raw, collection, withWorkspace)
+	if err == nil || stateName == "" {
+		return id, err
+	}
+
+	fallback.Collection = collection
+	fallback.Name = stateName
+	if !withWorkspace {
+		fallback.Workspace = ""
+	}
+	if fallback.Tenant == "" {
+		return ID{}, fmt.Errorf("tenant not given for resource %s", stateName)
+	}
+	if withWorkspace && fallback.Workspace == "" {
+		return ID{}, fmt.Errorf("workspace not given for resource %s", stateName)
+	}
+	return fallback, nil
+}
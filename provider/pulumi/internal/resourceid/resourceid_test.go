@@ -0,0 +1,117 @@
+package resourceid
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		id   ID
+		want string
+	}{
+		{ID{Tenant: "t1", Collection: "workspaces", Name: "ws-1"}, "tenants/t1/workspaces/ws-1"},
+		{ID{Tenant: "t1", Workspace: "ws-1", Collection: "block-storages", Name: "data-1"}, "tenants/t1/workspaces/ws-1/block-storages/data-1"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.id.Tenant, tt.id.Workspace, tt.id.Collection, tt.id.Name); got != tt.want {
+			t.Errorf("Format() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw           string
+		collection    string
+		withWorkspace bool
+		want          ID
+		wantErr       bool
+	}{
+		{
+			raw: "tenants/t1/workspaces/ws-1", collection: "workspaces",
+			want: ID{Tenant: "t1", Collection: "workspaces", Name: "ws-1"},
+		},
+		{
+			raw: " /tenants/t-1/workspaces/w-1/instances/vm-a-b/ ", collection: "instances", withWorkspace: true,
+			want: ID{Tenant: "t-1", Workspace: "w-1", Collection: "instances", Name: "vm-a-b"},
+		},
+		{raw: "tenants/t1/workspaces/ws1/instances/vm1", collection: "block-storages", withWorkspace: true, wantErr: true},
+		{raw: "tenants/t1/instances/vm1", collection: "instances", withWorkspace: true, wantErr: true},
+		{raw: "tenants/t1/workspaces/ws1/instances/vm1", collection: "workspaces", wantErr: true},
+		{raw: "tenants//workspaces/ws1", collection: "workspaces", wantErr: true},
+		{raw: "tenants/t1/workspaces//instances/vm1", collection: "instances", withWorkspace: true, wantErr: true},
+		// IDs before resource paths were used: tenant-workspace-kind-apiVersion-name.
+		{raw: "t1-ws1-instance-v1-vm1", collection: "instances", withWorkspace: true, wantErr: true},
+		{raw: "", collection: "workspaces", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw, tt.collection, tt.withWorkspace)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	id := ID{Tenant: "t1", Workspace: "ws-1", Collection: "instances", Name: "vm-1"}
+	got, err := Parse(id.String(), id.Collection, true)
+	if err != nil || got != id {
+		t.Fatalf("Parse(%q) = %+v, %v, want %+v", id.String(), got, err, id)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	fallback := ID{Tenant: "t1", Workspace: "ws1"}
+
+	tests := []struct {
+		name          string
+		raw           string
+		withWorkspace bool
+		stateName     string
+		fallback      ID
+		want          ID
+		wantErr       bool
+	}{
+		{
+			name: "resource path", raw: "tenants/t2/workspaces/ws2/instances/vm1", withWorkspace: true, stateName: "vm1", fallback: fallback,
+			want: ID{Tenant: "t2", Workspace: "ws2", Collection: "instances", Name: "vm1"},
+		},
+		{
+			name: "import", raw: "tenants/t2/workspaces/ws2/instances/vm1", withWorkspace: true, fallback: fallback,
+			want: ID{Tenant: "t2", Workspace: "ws2", Collection: "instances", Name: "vm1"},
+		},
+		{
+			name: "import of an invalid id", raw: "vm1", withWorkspace: true, fallback: fallback,
+			wantErr: true,
+		},
+		{
+			name: "legacy id", raw: "t1-ws1-instance-v1-vm1", withWorkspace: true, stateName: "vm1", fallback: fallback,
+			want: ID{Tenant: "t1", Workspace: "ws1", Collection: "instances", Name: "vm1"},
+		},
+		{
+			name: "legacy id of a tenant resource", raw: "t1-workspace-v1-ws1", stateName: "ws1", fallback: fallback,
+			want: ID{Tenant: "t1", Collection: "instances", Name: "ws1"},
+		},
+		{
+			name: "legacy id without workspace", raw: "t1-ws1-instance-v1-vm1", withWorkspace: true, stateName: "vm1", fallback: ID{Tenant: "t1"},
+			wantErr: true,
+		},
+		{
+			name: "legacy id without tenant", raw: "t1-ws1-instance-v1-vm1", withWorkspace: true, stateName: "vm1", fallback: ID{Workspace: "ws1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.raw, "instances", tt.withWorkspace, tt.stateName, tt.fallback)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 )
 
 const SchemasDir = "../../../../ext/secapi/spec/schemas"
+const SpecDir = "../../../../ext/secapi/spec"
 const PulumiControlResourceFile = "../../pulumi.gen.yaml"
 
 func main() {
@@ -24,7 +25,7 @@ func main() {
 	if !filepath.IsAbs(root) {
 		root = filepath.Join(cwd, root)
 	}
-	skipSchemas := loadPulumiControlResources(filepath.Join(cwd, PulumiControlResourceFile))
+	skipSchemas := loadPulumiControlResources(filepath.Join(cwd, PulumiControlResourceFile), filepath.Join(cwd, SpecDir))
 
 	models := codegen.GetModelsForPath(root)
 	resolver := codegen.NewSchemaResolver(models)
@@ -41,20 +42,14 @@ func main() {
 	}
 }
 
-func loadPulumiControlResources(path string) map[string]bool {
-	data, err := os.ReadFile(path)
+func loadPulumiControlResources(controlPath, specRoot string) map[string]bool {
+	discovery, err := codegen.Discover(controlPath, specRoot)
 	if err != nil {
-		return map[string]bool{}
-	}
-	var doc struct {
-		Resources map[string]any `yaml:"resources"`
-	}
-	if err := yaml.Unmarshal(data, &doc); err != nil {
 		fmt.Printf("error reading pulumi control resources: %v\n", err)
 		return map[string]bool{}
 	}
-	skip := make(map[string]bool, len(doc.Resources))
-	for name := range doc.Resources {
+	skip := make(map[string]bool, len(discovery.Resources))
+	for name := range discovery.Resources {
 		skip[name] = true
 	}
 	return skip